@@ -1,15 +1,10 @@
 package gopool
 
-import (
-	"errors"
-	"strings"
-)
+import "github.com/karrick/gopool/typed"
 
-// ChanPool implements the Pool interface, maintaining a pool of resources.
-type ChanPool struct {
-	ch chan interface{}
-	pc config
-}
+// ChanPool implements the Pool interface, maintaining a pool of resources. It is the interface{}
+// instantiation of typed.ChanPool.
+type ChanPool = typed.ChanPool[interface{}]
 
 // New creates a new Pool. The factory method used to create new items for the Pool must be
 // specified using the gopool.Factory method. Optionally, the pool size and a reset function can be
@@ -83,71 +78,9 @@ type ChanPool struct {
 //		return nil
 //	}
 func New(setters ...Configurator) (Pool, error) {
-	pc := &config{
-		size: DefaultSize,
-	}
-	for _, setter := range setters {
-		if err := setter(pc); err != nil {
-			return nil, err
-		}
-	}
-	if pc.factory == nil {
-		return nil, errors.New("ought to specify factory method")
-	}
-	pool := &ChanPool{
-		ch: make(chan interface{}, pc.size),
-		pc: *pc,
-	}
-	for i := 0; i < pool.pc.size; i++ {
-		item, err := pool.pc.factory()
-		if err != nil {
-			return nil, err
-		}
-		pool.ch <- item
+	pool, err := typed.NewChan(setters...)
+	if err != nil {
+		return nil, err
 	}
 	return pool, nil
 }
-
-// Get acquires and returns an item from the pool of resources. Get blocks while there are no items in the pool.
-func (pool *ChanPool) Get() interface{} {
-	return <-pool.ch
-}
-
-// Put will release a resource back to the pool. Put blocks if pool already full. If the Pool was
-// initialized with a Reset function, it will be invoked with the resource as its sole argument,
-// prior to the resource being added back to the pool. If Put is called when adding the resource to
-// the pool _would_ result in having more elements in the pool than the pool size, the resource is
-// effectively dropped on the floor after calling any optional Reset and Close methods on the
-// resource.
-func (pool *ChanPool) Put(item interface{}) {
-	if pool.pc.reset != nil {
-		pool.pc.reset(item)
-	}
-	pool.ch <- item
-}
-
-// Close is called when the Pool is no longer needed, and the resources in the Pool ought to be
-// released.  If a Pool has a close function, it will be invoked one time for each resource, with
-// that resource as its sole argument.
-func (pool *ChanPool) Close() error {
-	var errs []error
-	for {
-		select {
-		case item := <-pool.ch:
-			if pool.pc.close != nil {
-				if err := pool.pc.close(item); err != nil {
-					errs = append(errs, err)
-				}
-			}
-		default:
-			if len(errs) == 0 {
-				return nil
-			}
-			var messages []string
-			for _, err := range errs {
-				messages = append(messages, err.Error())
-			}
-			return errors.New(strings.Join(messages, ", "))
-		}
-	}
-}
@@ -1,8 +1,11 @@
 package gopool_test
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/karrick/gopool"
 )
@@ -91,6 +94,90 @@ func TestChanPoolInvokesClose(t *testing.T) {
 	}
 }
 
+func TestChanPoolGetContextCanceled(t *testing.T) {
+	pool, err := gopool.New(gopool.Size(1),
+		gopool.Factory(func() (interface{}, error) {
+			return nil, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := pool.(gopool.ContextPool)
+	item := pool.Get()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := cp.GetContext(ctx); err != context.Canceled {
+		t.Errorf("Actual: %#v; Expected: %#v", err, context.Canceled)
+	}
+
+	pool.Put(item)
+	if _, err := cp.GetContext(context.Background()); err != nil {
+		t.Errorf("Actual: %#v; Expected: %#v", err, nil)
+	}
+}
+
+func TestChanPoolGetContextDeadline(t *testing.T) {
+	pool, err := gopool.New(gopool.Size(1),
+		gopool.Factory(func() (interface{}, error) {
+			return nil, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := pool.(gopool.ContextPool)
+	_ = pool.Get()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cp.GetContext(ctx)
+	if !errors.Is(err, gopool.ErrTimeout) {
+		t.Errorf("Actual: %#v; Expected: %#v", err, gopool.ErrTimeout)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Actual: %#v; Expected: %#v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestChanPoolGetContextDoesNotLoseItems(t *testing.T) {
+	const size = 4
+	pool, err := gopool.New(gopool.Size(size), gopool.Factory(makeBuffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := pool.(gopool.ContextPool)
+
+	var wg sync.WaitGroup
+	wg.Add(lowConcurrency)
+	for c := 0; c < lowConcurrency; c++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Microsecond)
+				item, err := cp.GetContext(ctx)
+				cancel()
+				if err == nil {
+					pool.Put(item)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every item ought to still be available
+	for i := 0; i < size; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := cp.GetContext(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("item %d: Actual: %#v; Expected: %#v", i, err, nil)
+		}
+	}
+}
+
 func TestChanPool(t *testing.T) {
 	pool, err := gopool.New(gopool.Factory(makeBuffer), gopool.Reset(resetBuffer), gopool.Close(closeBuffer))
 	if err != nil {
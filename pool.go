@@ -1,9 +1,122 @@
 package gopool
 
-import "fmt"
+import (
+	"context"
+	"time"
+
+	"github.com/karrick/gopool/typed"
+)
 
 // DefaultSize is the default number of items that will be maintained in the pool.
-const DefaultSize = 10
+const DefaultSize = typed.DefaultSize
+
+// ErrClosed is returned when attempting to get an item from a Pool that has been closed, including
+// to callers that were blocked waiting for an item when the Pool was closed.
+var ErrClosed = typed.ErrClosed
+
+// ErrExhausted is returned when attempting to get an item without blocking from a Pool that has no
+// idle items and cannot create more.
+var ErrExhausted = typed.ErrExhausted
+
+// ErrInvalidSize is wrapped by the error returned when creating a Pool with an invalid size, maximum
+// size, minimum number of idle items, or overflow.
+var ErrInvalidSize = typed.ErrInvalidSize
+
+// ErrNoFactory is returned when creating a Pool without specifying a factory method.
+var ErrNoFactory = typed.ErrNoFactory
+
+// ErrNotCheckedOut is returned when discarding an item that is not checked out of a Pool, for
+// instance because it has already been released or discarded.
+var ErrNotCheckedOut = typed.ErrNotCheckedOut
+
+// ErrTimeout is wrapped by the error returned when a context's deadline expires while waiting for an
+// item from a Pool. The error also wraps context.DeadlineExceeded.
+var ErrTimeout = typed.ErrTimeout
+
+// FillError is returned when the factory fails while filling a new Pool. The items created before the
+// failure have already been passed to the optional close function.
+type FillError = typed.FillError
+
+// CloseError is returned when the close function returns an error for one or more items while
+// closing a Pool. It is the interface{} instantiation of typed.CloseError.
+type CloseError = typed.CloseError[interface{}]
+
+// OutstandingError is returned by Shutdown when its context is done before every checked out item
+// has been returned to the pool. It is the interface{} instantiation of typed.OutstandingError.
+type OutstandingError = typed.OutstandingError[interface{}]
+
+// Stats describes a pool, counting events since the pool was created.
+type Stats = typed.Stats
+
+// HistogramBuckets is the number of buckets in a Histogram.
+const HistogramBuckets = typed.HistogramBuckets
+
+// Histogram counts durations in buckets with fixed bounds, such as the time callers spend blocked
+// waiting for an item from a pool.
+type Histogram = typed.Histogram
+
+// ErrReleased is returned when releasing or discarding a Lease that has already been released or
+// discarded.
+var ErrReleased = typed.ErrReleased
+
+// Lease holds an item checked out of a pool by Acquire or AcquireContext, and returns it to the
+// pool at most one time. It is the interface{} instantiation of typed.Lease.
+type Lease = typed.Lease[interface{}]
+
+// Borrow describes an item checked out of a pool that tracks borrowers. It is the interface{}
+// instantiation of typed.Borrow.
+type Borrow = typed.Borrow[interface{}]
+
+// PanicError is returned in place of the error from a callback that panics, and is reported to the
+// optional OnPanic hook.
+type PanicError = typed.PanicError
+
+// LifecycleHooks holds optional functions called by a pool as items move through it. It is the
+// interface{} instantiation of typed.LifecycleHooks.
+type LifecycleHooks = typed.LifecycleHooks[interface{}]
+
+// DiscardReason describes why a pool discarded an item.
+type DiscardReason = typed.DiscardReason
+
+const (
+	DiscardCaller   = typed.DiscardCaller   // caller passed the item to Discard
+	DiscardClosed   = typed.DiscardClosed   // pool was closed, or could not be filled
+	DiscardExpired  = typed.DiscardExpired  // item exceeded its MaxIdleTime or MaxLifetime
+	DiscardInvalid  = typed.DiscardInvalid  // item failed ValidateOnGet or ValidateOnPut
+	DiscardRejected = typed.DiscardRejected // ResetOrDiscard rejected the item
+	DiscardPanicked = typed.DiscardPanicked // callback invoked with the item panicked
+	DiscardFull     = typed.DiscardFull     // pool had no room for the item, such as a temporary item
+)
+
+// Ordering determines which idle item a pool hands out next.
+type Ordering = typed.Ordering
+
+const (
+	FIFO   = typed.FIFO   // hand out the item idle the longest
+	LIFO   = typed.LIFO   // hand out the item most recently released back to the pool
+	Random = typed.Random // hand out an idle item chosen at random
+)
+
+// RetryPolicy describes how a pool retries failed factory calls, and when it stops calling the
+// factory altogether.
+type RetryPolicy = typed.RetryPolicy
+
+// BreakerState is the state of the circuit breaker of a FactoryRetry policy.
+type BreakerState = typed.BreakerState
+
+const (
+	BreakerClosed   = typed.BreakerClosed   // factory is called as needed
+	BreakerOpen     = typed.BreakerOpen     // factory is not called
+	BreakerHalfOpen = typed.BreakerHalfOpen // factory is called once to probe whether it recovered
+)
+
+// ErrBreakerOpen is wrapped by the *BreakerOpenError returned instead of calling the factory while
+// the circuit breaker of a FactoryRetry policy is open.
+var ErrBreakerOpen = typed.ErrBreakerOpen
+
+// BreakerOpenError is returned instead of calling the factory while the circuit breaker of a
+// FactoryRetry policy is open.
+type BreakerOpenError = typed.BreakerOpenError
 
 // Pool is the interface implemented by an object that acts as a free-list resource pool.
 type Pool interface {
@@ -12,32 +125,144 @@ type Pool interface {
 	Put(interface{})
 }
 
-type config struct {
-	close   func(interface{}) error
-	factory func() (interface{}, error)
-	reset   func(interface{})
-	size    int
+// ContextPool is the interface implemented by a Pool that allows a caller to abandon waiting for a
+// resource when the provided context is canceled or its deadline expires.
+//
+// GetContext returns context.Canceled when the context is canceled, and an error that wraps both
+// ErrTimeout and context.DeadlineExceeded when its deadline expires, whether while waiting for an
+// item or while the factory creates one. Use errors.Is to test for either of them.
+type ContextPool interface {
+	Pool
+	GetContext(context.Context) (interface{}, error)
+}
+
+// LeasePool is the interface implemented by a Pool that hands out items wrapped in a Lease, which
+// returns its item to the pool at most one time.
+type LeasePool interface {
+	Pool
+	Acquire() (*Lease, error)
+	AcquireContext(context.Context) (*Lease, error)
 }
 
-// Configurator is a function that modifies a pool configuration structure.
-type Configurator func(*config) error
+// Configurator is a function that modifies a pool configuration structure. It is the interface{}
+// instantiation of typed.Configurator, so configurators from the typed package may also be used.
+type Configurator = typed.Configurator[interface{}]
 
 // Close specifies the optional function to be called once for each resource when the Pool is
 // closed.
 func Close(close func(interface{}) error) Configurator {
-	return func(pc *config) error {
-		pc.close = close
-		return nil
-	}
+	return typed.Close(close)
 }
 
 // Factory specifies the function used to make new elements for the pool.  The factory function is
-// called to fill the pool N times during initialization, for a pool size of N.
+// called to fill the pool N times during initialization, for a pool size of N. When either MinIdle
+// or MaxSize is specified, the factory function is instead called to create items on demand.
 func Factory(factory func() (interface{}, error)) Configurator {
-	return func(pc *config) error {
-		pc.factory = factory
-		return nil
-	}
+	return typed.Factory(factory)
+}
+
+// ConstructionContext specifies the parent of the context passed to a FactoryContext factory when
+// creating items for no particular caller, namely while filling the pool during initialization,
+// and while creating idle items in the background. Canceling ctx abandons creating those items. By
+// default, the parent context is context.Background.
+func ConstructionContext(ctx context.Context) Configurator {
+	return typed.ConstructionContext[interface{}](ctx)
+}
+
+// ExpiryJitter specifies the maximum random duration subtracted from the maximum idle time and
+// maximum lifetime of each item, so that items created or returned to the pool at the same time do
+// not all expire at the same time.
+func ExpiryJitter(jitter time.Duration) Configurator {
+	return typed.ExpiryJitter[interface{}](jitter)
+}
+
+// FactoryContext specifies the function used to make new elements for the pool, like Factory, but
+// for a factory that accepts a context, so that creating an item may be abandoned. When GetContext
+// creates an item, the factory is passed the caller's context. Otherwise the factory is passed a
+// context derived from the ConstructionContext, which is canceled when the pool is closed, or
+// while filling the pool during initialization, when the FillTimeout elapses.
+func FactoryContext(factory func(context.Context) (interface{}, error)) Configurator {
+	return typed.FactoryContext(factory)
+}
+
+// FactoryRetry specifies how the pool retries failed factory calls using exponential backoff with
+// jitter, and when a circuit breaker stops calling the factory altogether, so callers fail fast
+// rather than waiting on a factory that keeps failing. TryGet and TryAcquire, which must not block,
+// call the factory only once.
+func FactoryRetry(policy RetryPolicy) Configurator {
+	return typed.FactoryRetry[interface{}](policy)
+}
+
+// FillConcurrency specifies the maximum number of concurrent factory calls made to create the
+// initial items of the pool. By default, the initial items are created one after another.
+func FillConcurrency(n int) Configurator {
+	return typed.FillConcurrency[interface{}](n)
+}
+
+// FillTimeout specifies the maximum duration allowed to create the initial items of the pool. When
+// it elapses, no more factory calls are made, the items already created are passed to the optional
+// close function, and creating the pool fails with a *FillError that wraps ErrTimeout. Items
+// returned by factory calls outstanding at that time are passed to the close function as those
+// calls return.
+func FillTimeout(d time.Duration) Configurator {
+	return typed.FillTimeout[interface{}](d)
+}
+
+// Hooks specifies the optional functions called by the pool as items move through it.
+func Hooks(hooks LifecycleHooks) Configurator {
+	return typed.Hooks(hooks)
+}
+
+// MaxIdleTime specifies the maximum duration an item may remain idle in the pool. A background
+// reaper passes each item that remains idle longer than this to the optional close function, and
+// the pool creates replacement items as needed.
+func MaxIdleTime(d time.Duration) Configurator {
+	return typed.MaxIdleTime[interface{}](d)
+}
+
+// MaxLifetime specifies the maximum duration an item may be used after being created. A background
+// reaper passes each idle item older than this to the optional close function, as does Put for each
+// released item older than this, and the pool creates replacement items as needed. See the typed
+// package documentation for how this applies to items that cannot be compared.
+func MaxLifetime(d time.Duration) Configurator {
+	return typed.MaxLifetime[interface{}](d)
+}
+
+// MaxSize specifies the maximum number of items the pool may hold, and causes the pool to create
+// items on demand rather than filling the pool during initialization. MaxSize takes precedence
+// over Size.
+func MaxSize(size int) Configurator {
+	return typed.MaxSize[interface{}](size)
+}
+
+// MinIdle specifies the number of idle items the pool keeps warm, and causes the pool to create
+// items on demand rather than filling the pool during initialization. Whenever the number of idle
+// items drops below it, the pool creates more items in the background, so long as the pool does not
+// exceed its maximum size.
+func MinIdle(count int) Configurator {
+	return typed.MinIdle[interface{}](count)
+}
+
+// OnPanic specifies the optional function to be called when a callback, such as the factory, reset,
+// validation, or close function, panics. The panic is recovered and converted to a *PanicError,
+// which is passed to the hook, and returned in place of the error from the callback, if any. The
+// item the callback was invoked with, if any, is discarded, and the pool remains usable.
+func OnPanic(hook func(*PanicError)) Configurator {
+	return typed.OnPanic[interface{}](hook)
+}
+
+// Order specifies which idle item the pool hands out next. By default, pools are FIFO.
+func Order(order Ordering) Configurator {
+	return typed.Order[interface{}](order)
+}
+
+// Overflow specifies the number of temporary items the pool may create beyond its capacity. When
+// every item is checked out and the pool already holds its maximum number of items, Get creates a
+// temporary item from the factory rather than waiting for an item to be returned to the pool, so
+// long as fewer than maxExtra temporary items are checked out. When a temporary item is released
+// back to a full pool, it is passed to the optional reset and close functions and dropped.
+func Overflow(maxExtra int) Configurator {
+	return typed.Overflow[interface{}](maxExtra)
 }
 
 // Reset specifies the optional function to be called on resources when released back to the pool.
@@ -46,19 +271,50 @@ func Factory(factory func() (interface{}, error)) Configurator {
 // function invoke the buffer's Reset method to free resources prior to returning the buffer to the
 // Pool.
 func Reset(reset func(interface{})) Configurator {
-	return func(pc *config) error {
-		pc.reset = reset
-		return nil
-	}
+	return typed.Reset(reset)
+}
+
+// ResetOrDiscard specifies the optional function to be called on resources when released back to
+// the pool, after any reset function, which may reject a resource that ought not be reused by
+// returning an error. For instance, a pool of buffers may reject a buffer that grew too large while
+// in use, so it does not hold on to that memory. A rejected resource is passed to the optional close
+// function rather than being added back to the pool, and the pool creates replacement resources
+// from the factory as needed.
+func ResetOrDiscard(reset func(interface{}) error) Configurator {
+	return typed.ResetOrDiscard(reset)
 }
 
 // Size specifies the number of items to maintain in the pool.
 func Size(size int) Configurator {
-	return func(pc *config) error {
-		if size <= 0 {
-			return fmt.Errorf("pool size must be greater than 0: %d", size)
-		}
-		pc.size = size
-		return nil
-	}
+	return typed.Size[interface{}](size)
+}
+
+// Stack specifies whether the pool hands out the idle item most recently released back to the
+// pool, like a stack, rather than the item idle the longest, like a queue, which is the default.
+// Stack(true) is equivalent to Order(LIFO), and Stack(false) to Order(FIFO).
+func Stack(stack bool) Configurator {
+	return typed.Stack[interface{}](stack)
+}
+
+// TrackBorrowers specifies whether the pool records the time and call stack of each Get, so that
+// items held longer than expected, for instance because a caller neglected to release them back to
+// the pool, can be listed by the pool's Outstanding method along with where they were checked out.
+// Items that cannot be compared, such as byte slices, are not tracked.
+func TrackBorrowers(track bool) Configurator {
+	return typed.TrackBorrowers[interface{}](track)
+}
+
+// ValidateOnGet specifies the optional function to be called on an idle resource before handing it
+// to a caller. When validation fails, the resource is passed to the optional close function, the
+// caller receives another resource, and the pool creates replacement resources as needed.
+func ValidateOnGet(validate func(interface{}) error) Configurator {
+	return typed.ValidateOnGet(validate)
+}
+
+// ValidateOnPut specifies the optional function to be called on a resource released back to the
+// pool, after any reset function. When validation fails, the resource is passed to the optional
+// close function rather than being added back to the pool, and the pool creates replacement
+// resources as needed.
+func ValidateOnPut(validate func(interface{}) error) Configurator {
+	return typed.ValidateOnPut(validate)
 }
@@ -70,3 +70,37 @@ func bench(b *testing.B, bp gopool.Pool, concurrency int) {
 	b.ResetTimer() // do not include initialization time in benchmarks
 	testC(bp, concurrency, b.N)
 }
+
+func TestPoolsAcceptItemsHoldingUnhashableValues(t *testing.T) {
+	// conn is comparable, but hashing a conn panics when meta holds a slice.
+	type conn struct {
+		meta interface{}
+	}
+	constructors := []struct {
+		name string
+		new  func(...gopool.Configurator) (gopool.Pool, error)
+	}{
+		{"ChanPool", gopool.New},
+		{"ArrayPool", gopool.NewArrayPool},
+		{"SemaphorePool", gopool.NewSemaphorePool},
+	}
+	for _, c := range constructors {
+		t.Run(c.name, func(t *testing.T) {
+			pool, err := c.new(gopool.Size(1),
+				gopool.Factory(func() (interface{}, error) {
+					return conn{meta: []byte("x")}, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			item := pool.Get()
+			pool.Put(item)
+			item = pool.Get()
+			if _, ok := item.(conn); !ok {
+				t.Errorf("Actual: %#v; Expected: %T", item, conn{})
+			}
+		})
+	}
+}
@@ -1,126 +1,210 @@
 package gopool_test
 
 import (
-	"bytes"
-	"fmt"
+	"context"
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/karrick/gopool"
 )
 
-// EXPERIMENT
-
-type SemaphorePool struct {
-	free       []interface{}
-	getc, putc *sync.Cond // NOTE: both uses same underlying mutex
-	count, max int
+func TestSemaphorePoolErrorWithoutFactory(t *testing.T) {
+	pool, err := gopool.NewSemaphorePool()
+	if pool != nil {
+		t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+	}
+	if err == nil {
+		t.Errorf("Actual: %#v; Expected: %#v", err, "not nil")
+	}
 }
 
-func NewSemaphorePool(items []interface{}) (*SemaphorePool, error) {
-	m := new(sync.Mutex)
+func TestSemaphorePoolErrorWithNonPositiveSize(t *testing.T) {
+	pool, err := gopool.NewSemaphorePool(gopool.Size(0))
+	if pool != nil {
+		t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+	}
+	if err == nil {
+		t.Errorf("Actual: %#v; Expected: %#v", err, "not nil")
+	}
 
-	s := &SemaphorePool{
-		free:  make([]interface{}, len(items)),
-		getc:  &sync.Cond{L: m},
-		putc:  &sync.Cond{L: m},
-		count: len(items),
-		max:   len(items),
+	pool, err = gopool.NewSemaphorePool(gopool.Size(-1))
+	if pool != nil {
+		t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
 	}
-	if n := copy(s.free, items); n < len(items) {
-		return nil, fmt.Errorf("only copied %d out of %d items", n, len(items))
+	if err == nil {
+		t.Errorf("Actual: %#v; Expected: %#v", err, "not nil")
 	}
-	return s, nil
 }
 
-func (p *SemaphorePool) Close() error {
-	p.getc.L.Lock()
-	p.free = nil
-
-	// Set count and max such that both Get() and Put() would not block
-	p.count = 1
-	p.max = 2
-
-	// Unlock all calls to Get() and Put() to prevent go routine leak
-	p.getc.L.Unlock()
-	p.getc.Broadcast()
-	p.putc.Broadcast()
+func TestSemaphorePoolCreatesSizeItems(t *testing.T) {
+	var size = 42
+	var factoryInvoked int
+	_, err := gopool.NewSemaphorePool(gopool.Size(size),
+		gopool.Factory(func() (interface{}, error) {
+			factoryInvoked++
+			return nil, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	return nil
+	if actual, expected := factoryInvoked, size; actual != expected {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
 }
 
-const inplace = false
-
-func (p *SemaphorePool) Get() (item interface{}) {
-	p.getc.L.Lock()
-	for p.count == 0 {
-		p.getc.Wait()
+func TestSemaphorePoolInvokesReset(t *testing.T) {
+	var resetInvoked int
+	pool, err := gopool.NewSemaphorePool(
+		gopool.Factory(func() (interface{}, error) {
+			return nil, nil
+		}),
+		gopool.Reset(func(item interface{}) {
+			resetInvoked++
+		}))
+	if err != nil {
+		t.Fatal(err)
 	}
-	if p.free == nil {
-		panic("Get() after Close()")
+	pool.Put(pool.Get())
+	if actual, expected := resetInvoked, 1; actual != expected {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
 	}
+}
 
-	p.count--
-	if inplace {
-		item = p.free[p.count]
-	} else {
-		item, p.free = p.free[0], p.free[1:]
+func TestSemaphorePoolInvokesClose(t *testing.T) {
+	var closeInvoked int
+	pool, err := gopool.NewSemaphorePool(gopool.Size(1),
+		gopool.Factory(func() (interface{}, error) {
+			return nil, nil
+		}),
+		gopool.Close(func(_ interface{}) error {
+			closeInvoked++
+			return errors.New("foo")
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Close(); err == nil || err.Error() != "foo" {
+		t.Errorf("Actual: %#v; Expected: %#v", err, "foo")
+	}
+	if actual, expected := closeInvoked, 1; actual != expected {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
 	}
-
-	p.getc.L.Unlock()
-	p.putc.Signal()
-
-	return item
 }
 
-func (p *SemaphorePool) Put(item interface{}) {
-	p.putc.L.Lock()
-	for p.count == p.max {
-		p.putc.Wait()
+func TestSemaphorePoolGetContextCanceled(t *testing.T) {
+	pool, err := gopool.NewSemaphorePool(gopool.Size(1),
+		gopool.Factory(func() (interface{}, error) {
+			return nil, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
 	}
-	if p.free == nil {
-		panic("Put() after Close()")
+	cp := pool.(gopool.ContextPool)
+	item := pool.Get()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := cp.GetContext(ctx); err != context.Canceled {
+		t.Errorf("Actual: %#v; Expected: %#v", err, context.Canceled)
 	}
 
-	if inplace {
-		p.free[p.count] = item
-	} else {
-		p.free = append(p.free, item)
+	pool.Put(item)
+	if _, err := cp.GetContext(context.Background()); err != nil {
+		t.Errorf("Actual: %#v; Expected: %#v", err, nil)
 	}
-	p.count++
-
-	p.putc.L.Unlock()
-	p.getc.Signal()
 }
 
-// TESTING
-
-func newSemaphorePool(tb testing.TB, count int) gopool.Pool {
-	items := make([]interface{}, count)
-	for i := 0; i < count; i++ {
-		items[i] = bytes.NewBuffer(make([]byte, defaultBufSize))
+func TestSemaphorePoolGetContextDeadline(t *testing.T) {
+	pool, err := gopool.NewSemaphorePool(gopool.Size(1),
+		gopool.Factory(func() (interface{}, error) {
+			return nil, nil
+		}))
+	if err != nil {
+		t.Fatal(err)
 	}
+	cp := pool.(gopool.ContextPool)
+	_ = pool.Get()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = cp.GetContext(ctx)
+	if !errors.Is(err, gopool.ErrTimeout) {
+		t.Errorf("Actual: %#v; Expected: %#v", err, gopool.ErrTimeout)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Actual: %#v; Expected: %#v", err, context.DeadlineExceeded)
+	}
+}
 
-	pool, err := NewSemaphorePool(items)
+func TestSemaphorePoolGetContextDoesNotLoseItems(t *testing.T) {
+	const size = 4
+	pool, err := gopool.NewSemaphorePool(gopool.Size(size), gopool.Factory(makeBuffer))
 	if err != nil {
-		tb.Fatal(err)
+		t.Fatal(err)
+	}
+	cp := pool.(gopool.ContextPool)
+
+	var wg sync.WaitGroup
+	wg.Add(lowConcurrency)
+	for c := 0; c < lowConcurrency; c++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Microsecond)
+				item, err := cp.GetContext(ctx)
+				cancel()
+				if err == nil {
+					pool.Put(item)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// every item ought to still be available
+	for i := 0; i < size; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := cp.GetContext(ctx)
+		cancel()
+		if err != nil {
+			t.Fatalf("item %d: Actual: %#v; Expected: %#v", i, err, nil)
+		}
 	}
-
-	return pool
 }
 
 func TestSemaphorePool(t *testing.T) {
-	test(t, newSemaphorePool(t, lowCap))
+	pool, err := gopool.NewSemaphorePool(gopool.Factory(makeBuffer), gopool.Reset(resetBuffer), gopool.Close(closeBuffer))
+	if err != nil {
+		t.Fatalf("Actual: %#v; Expected: %#v", err, nil)
+	}
+	test(t, pool)
+}
+
+func TestSemaphorePoolSize(t *testing.T) {
+	pool, err := gopool.NewSemaphorePool(gopool.Factory(makeBuffer), gopool.Reset(resetBuffer), gopool.Close(closeBuffer), gopool.Size(lowCap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	test(t, pool)
 }
 
 func BenchmarkSemaphoreLowConcurrency(b *testing.B) {
-	bench(b, newSemaphorePool(b, lowCap), lowConcurrency)
+	pool, _ := gopool.NewSemaphorePool(gopool.Factory(makeBuffer), gopool.Reset(resetBuffer), gopool.Close(closeBuffer), gopool.Size(lowCap))
+	bench(b, pool, lowConcurrency)
 }
 
 func BenchmarkSemaphoreMediumConcurrency(b *testing.B) {
-	bench(b, newSemaphorePool(b, medCap), medConcurrency)
+	pool, _ := gopool.NewSemaphorePool(gopool.Factory(makeBuffer), gopool.Reset(resetBuffer), gopool.Close(closeBuffer), gopool.Size(medCap))
+	bench(b, pool, medConcurrency)
 }
 
 func BenchmarkSemaphoreHighConcurrency(b *testing.B) {
-	bench(b, newSemaphorePool(b, largeCap), highConcurrency)
+	pool, _ := gopool.NewSemaphorePool(gopool.Factory(makeBuffer), gopool.Reset(resetBuffer), gopool.Close(closeBuffer), gopool.Size(largeCap))
+	bench(b, pool, highConcurrency)
 }
@@ -0,0 +1,170 @@
+package typed
+
+import "math/rand"
+
+const (
+	putBlocks = iota
+	getBocks
+	neitherBlocks
+)
+
+// ArrayPool implements the Pool interface, maintaining a pool of resources. Idle items are kept in
+// a ring buffer, and every caller blocked waiting for an item is woken when one becomes available.
+type ArrayPool[T any] struct {
+	condPool[T]
+}
+
+// NewArray creates a new Pool. The factory method used to create new items for the Pool must be
+// specified using the typed.Factory method. Optionally, the pool size and a reset function can be
+// specified. When either MinIdle or MaxSize is specified, items are created on demand rather than
+// during initialization. When the factory fails while filling the pool, the items already created
+// are passed to the optional close function, and a *FillError is returned.
+//
+//	package main
+//
+//	import (
+//		"bytes"
+//		"errors"
+//		"fmt"
+//		"log"
+//		"math/rand"
+//		"sync"
+//
+//		"github.com/karrick/gopool/typed"
+//	)
+//
+//	const (
+//		bufSize  = 64 * 1024
+//		poolSize = 25
+//	)
+//
+//	func main() {
+//		const iterationCount = 1000
+//		const parallelCount = 100
+//
+//		makeBuffer := func() (*bytes.Buffer, error) {
+//			return bytes.NewBuffer(make([]byte, 0, bufSize)), nil
+//		}
+//
+//		resetBuffer := func(bb *bytes.Buffer) {
+//			bb.Reset()
+//		}
+//
+//		bp, err := typed.NewArray(typed.Size[*bytes.Buffer](poolSize), typed.Factory(makeBuffer), typed.Reset(resetBuffer))
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//
+//		var wg sync.WaitGroup
+//		wg.Add(parallelCount)
+//
+//		for i := 0; i < parallelCount; i++ {
+//			go func() {
+//				defer wg.Done()
+//
+//				for j := 0; j < iterationCount; j++ {
+//					if err := grabBufferAndUseIt(bp); err != nil {
+//						fmt.Println(err)
+//						return
+//					}
+//				}
+//			}()
+//		}
+//		wg.Wait()
+//	}
+//
+//	func grabBufferAndUseIt(pool typed.Pool[*bytes.Buffer]) error {
+//		// WARNING: Must ensure resource returns to pool otherwise gopool will deadlock once all
+//		// resources used.
+//		bb := pool.Get()
+//		defer pool.Put(bb) // IMPORTANT: defer here to ensure invoked even when subsequent code bails
+//
+//		for k := 0; k < bufSize; k++ {
+//			if rand.Intn(100000000) == 1 {
+//				return errors.New("random error to illustrate need to return resource to pool")
+//			}
+//			bb.WriteByte(byte(k % 256))
+//		}
+//		return nil
+//	}
+func NewArray[T any](setters ...Configurator[T]) (Pool[T], error) {
+	pc, err := newConfig(setters)
+	if err != nil {
+		return nil, err
+	}
+	pool := new(ArrayPool[T])
+	if err := pool.start(pc, &ring[T]{blocked: getBocks, items: make([]entry[T], pc.size)}, false); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// ring holds the idle entries of an ArrayPool in a ring buffer with room for every item in the pool.
+type ring[T any] struct {
+	blocked int // putBlocks | getBlocks | neitherBlocks
+	gi      int // index of next Get
+	pi      int // index of next Put
+	items   []entry[T]
+}
+
+// len returns the number of idle entries.
+func (r *ring[T]) len() int {
+	switch r.blocked {
+	case getBocks:
+		return 0
+	case putBlocks:
+		return len(r.items)
+	default:
+		return (r.pi - r.gi + len(r.items)) % len(r.items)
+	}
+}
+
+// take removes and returns the idle entry chosen according to order.
+func (r *ring[T]) take(order Ordering) entry[T] {
+	switch order {
+	case LIFO:
+		var zero entry[T]
+		r.pi = (r.pi - 1 + len(r.items)) % len(r.items)
+		e := r.items[r.pi]
+		r.items[r.pi] = zero // do not retain reference to item while checked out
+		if r.gi == r.pi {
+			r.blocked = getBocks
+		} else {
+			r.blocked = neitherBlocks
+		}
+		return e
+	case Random:
+		// Move a random idle entry to the index of the next Get.
+		i := (r.gi + rand.Intn(r.len())) % len(r.items)
+		r.items[r.gi], r.items[i] = r.items[i], r.items[r.gi]
+	}
+	return r.shift()
+}
+
+// shift removes and returns the entry at the index of the next Get, which has been idle the
+// longest.
+func (r *ring[T]) shift() entry[T] {
+	var zero entry[T]
+	e := r.items[r.gi]
+	r.items[r.gi] = zero // do not retain reference to item while checked out
+
+	r.gi = (r.gi + 1) % len(r.items)
+	if r.gi == r.pi {
+		r.blocked = getBocks
+	} else {
+		r.blocked = neitherBlocks
+	}
+	return e
+}
+
+// push adds e at the index of the next Put.
+func (r *ring[T]) push(e entry[T]) {
+	r.items[r.pi] = e
+
+	r.pi = (r.pi + 1) % len(r.items)
+	if r.gi == r.pi {
+		r.blocked = putBlocks
+	} else {
+		r.blocked = neitherBlocks
+	}
+}
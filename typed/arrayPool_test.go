@@ -0,0 +1,23 @@
+package typed_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/karrick/gopool/typed"
+)
+
+func BenchmarkArrayLowConcurrency(b *testing.B) {
+	pool, _ := typed.NewArray(typed.Factory(makeBuffer), typed.Reset(resetBuffer), typed.Close(closeBuffer), typed.Size[*bytes.Buffer](lowCap))
+	bench(b, pool, lowConcurrency)
+}
+
+func BenchmarkArrayMediumConcurrency(b *testing.B) {
+	pool, _ := typed.NewArray(typed.Factory(makeBuffer), typed.Reset(resetBuffer), typed.Close(closeBuffer), typed.Size[*bytes.Buffer](medCap))
+	bench(b, pool, medConcurrency)
+}
+
+func BenchmarkArrayHighConcurrency(b *testing.B) {
+	pool, _ := typed.NewArray(typed.Factory(makeBuffer), typed.Reset(resetBuffer), typed.Close(closeBuffer), typed.Size[*bytes.Buffer](largeCap))
+	bench(b, pool, highConcurrency)
+}
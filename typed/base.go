@@ -0,0 +1,163 @@
+package typed
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// base holds the configuration and counters shared by every pool implementation, and invokes the
+// configured callbacks on behalf of the pool, counting each invocation.
+type base[T any] struct {
+	counters counters // first, so its 64-bit fields are aligned on 32-bit platforms
+	pc       config[T]
+	breaker  breaker
+
+	// ctx is passed to the factory when creating items for no particular caller, and is canceled
+	// when the pool is closed.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// init prepares b for use with the configuration pc.
+func (b *base[T]) init(pc *config[T]) {
+	b.pc = *pc
+	b.ctx, b.cancel = context.WithCancel(pc.context)
+}
+
+// produce returns a new item from the factory function, retrying failed calls according to the
+// retry policy, unless the circuit breaker is open. When wait is false, produce calls the factory
+// only once, so callers that must not block are not delayed by the retry policy. The factory and
+// the delay between retries respect ctx, and when ctx is done, produce returns the error from
+// waitError rather than counting a factory failure.
+func (b *base[T]) produce(ctx context.Context, wait bool) (T, error) {
+	probe, err := b.breaker.allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	rp := &b.pc.retry
+	attempts := 1
+	if wait {
+		attempts = rp.attempts()
+	}
+	for retry := 1; ; retry++ {
+		atomic.AddUint64(&b.counters.factoryCalls, 1)
+		var item T
+		err := b.guard("Factory", func() (err error) {
+			item, err = b.pc.factory(ctx)
+			return err
+		})
+		if err == nil {
+			b.breaker.succeeded()
+			b.created(item)
+			return item, nil
+		}
+		if ctx.Err() != nil {
+			// The factory gave up because the caller did, which says nothing about the factory.
+			if probe {
+				b.breaker.abandoned()
+			}
+			return item, waitError(ctx)
+		}
+		atomic.AddUint64(&b.counters.factoryFailures, 1)
+		b.factoryFailed(err)
+		if retry >= attempts {
+			if b.breaker.failed(rp, err) {
+				atomic.AddUint64(&b.counters.breakerTrips, 1)
+			}
+			return item, err
+		}
+		atomic.AddUint64(&b.counters.factoryRetries, 1)
+		timer := time.NewTimer(rp.delay(retry))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if probe {
+				b.breaker.abandoned()
+			}
+			return item, waitError(ctx)
+		}
+	}
+}
+
+// stats returns a Stats populated from the counters and configuration. The caller is responsible
+// for populating the fields that describe the items in the pool.
+func (b *base[T]) stats() Stats {
+	st := b.counters.stats()
+	st.Capacity = b.pc.size
+	st.Breaker = b.breaker.current()
+	return st
+}
+
+// recycle prepares item released back to the pool for reuse by invoking the optional reset
+// functions, returning false along with the reason when the item must instead be discarded,
+// because ResetOrDiscard rejected it, it failed validation, or a callback panicked.
+func (b *base[T]) recycle(item T) (DiscardReason, bool) {
+	if b.pc.reset != nil {
+		err := b.guard("Reset", func() error {
+			b.pc.reset(item)
+			return nil
+		})
+		if err != nil {
+			return DiscardPanicked, false
+		}
+	}
+	if b.pc.resetOrDiscard != nil {
+		err := b.guard("ResetOrDiscard", func() error { return b.pc.resetOrDiscard(item) })
+		if err != nil {
+			if _, ok := err.(*PanicError); ok {
+				return DiscardPanicked, false
+			}
+			atomic.AddUint64(&b.counters.rejections, 1)
+			return DiscardRejected, false
+		}
+	}
+	return b.valid("ValidateOnPut", b.pc.validateOnPut, item)
+}
+
+// valid returns true when item passes validate, which was specified by the named configurator, or
+// when validate is nil, and otherwise returns false along with the reason to discard item. It
+// counts the items that fail validation, other than by panicking.
+func (b *base[T]) valid(callback string, validate func(T) error, item T) (DiscardReason, bool) {
+	if validate == nil {
+		return 0, true
+	}
+	err := b.guard(callback, func() error { return validate(item) })
+	if err == nil {
+		return 0, true
+	}
+	if _, ok := err.(*PanicError); ok {
+		return DiscardPanicked, false
+	}
+	atomic.AddUint64(&b.counters.validationFailures, 1)
+	return DiscardInvalid, false
+}
+
+// destroy invokes the optional OnDiscard hook with item and the reason it is discarded, then the
+// optional close function with item as its sole argument.
+func (b *base[T]) destroy(item T, reason DiscardReason) error {
+	b.discarded(item, reason)
+	if b.pc.close == nil {
+		return nil
+	}
+	atomic.AddUint64(&b.counters.closeCalls, 1)
+	return b.guard("Close", func() error { return b.pc.close(item) })
+}
+
+// closeEach passes each item, discarded for reason, to the close function, returning a *CloseError
+// when closing any of them returns an error, and otherwise nil.
+func (b *base[T]) closeEach(items []T, reason DiscardReason) error {
+	var ce CloseError[T]
+	for _, item := range items {
+		if err := b.destroy(item, reason); err != nil {
+			ce.Items = append(ce.Items, item)
+			ce.Errs = append(ce.Errs, err)
+		}
+	}
+	if len(ce.Errs) == 0 {
+		return nil
+	}
+	return &ce
+}
@@ -0,0 +1,67 @@
+package typed
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxBorrowerFrames is the maximum number of stack frames recorded for each Get when tracking
+// borrowers.
+const maxBorrowerFrames = 32
+
+// packagePrefix is the prefix of the names of the functions in this package.
+const packagePrefix = "github.com/karrick/gopool/typed."
+
+// Borrow describes an item checked out of a pool that tracks borrowers.
+type Borrow[T any] struct {
+	Item  T
+	Since time.Time // when the item was checked out
+	Stack []uintptr // program counters of the stack that checked out the item, from runtime.Callers
+}
+
+// StackTrace returns a description of the stack that checked out the item, one function per line
+// followed by its file and line number, omitting the frames within this package.
+func (b Borrow[T]) StackTrace() string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(b.Stack)
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) {
+			fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return sb.String()
+		}
+	}
+}
+
+// borrow records in e the time and call stack of the caller checking out its item, when the pool
+// tracks borrowers. It records only the time when the pool instead has an OnPut hook.
+func (b *base[T]) borrow(e *entry[T]) {
+	if !b.pc.trackBorrowers {
+		if b.pc.hooks.OnPut != nil {
+			e.borrowed = time.Now()
+		}
+		return
+	}
+	e.borrowed = time.Now()
+	e.stack = make([]uintptr, maxBorrowerFrames)
+	e.stack = e.stack[:runtime.Callers(2, e.stack)]
+}
+
+// outstanding returns the borrows in l of items checked out for longer than threshold, longest held
+// first.
+func outstanding[T any](l *ledger[T], threshold time.Duration) []Borrow[T] {
+	var borrows []Borrow[T]
+	cutoff := time.Now().Add(-threshold)
+	l.each(func(e entry[T]) {
+		if e.stack != nil && e.borrowed.Before(cutoff) {
+			borrows = append(borrows, Borrow[T]{Item: e.item, Since: e.borrowed, Stack: e.stack})
+		}
+	})
+	sort.Slice(borrows, func(i, j int) bool { return borrows[i].Since.Before(borrows[j].Since) })
+	return borrows
+}
@@ -0,0 +1,674 @@
+package typed
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChanPool implements the Pool interface, maintaining a pool of resources.
+type ChanPool[T any] struct {
+	base[T]
+
+	ch      chan entry[T] // holds each idle item, or when not FIFO, a placeholder for each idle item
+	slots   chan struct{} // holds one token for each item, both idle and checked out
+	room    chan struct{} // receives a token when a place for a temporary item is freed
+	done    chan struct{} // closed when the pool is closed
+	drained chan struct{} // closed when the pool is closed and no items remain checked out
+
+	lock          sync.Mutex // protects the following fields
+	closed        bool
+	drainedClosed bool
+	replenishing  bool
+	extra         int // number of temporary items created by Overflow, including those being created
+	ledger        ledger[T]
+	idle          []entry[T] // idle items when not FIFO, in the order they were released back to the pool
+}
+
+// NewChan creates a new Pool. The factory method used to create new items for the Pool must be
+// specified using the typed.Factory method. Optionally, the pool size and a reset function can be
+// specified. When either MinIdle or MaxSize is specified, items are created on demand rather than
+// during initialization. When the factory fails while filling the pool, the items already created
+// are passed to the optional close function, and a *FillError is returned.
+//
+//	package main
+//
+//	import (
+//		"bytes"
+//		"errors"
+//		"fmt"
+//		"log"
+//		"math/rand"
+//		"sync"
+//
+//		"github.com/karrick/gopool/typed"
+//	)
+//
+//	const (
+//		bufSize  = 64 * 1024
+//		poolSize = 25
+//	)
+//
+//	func main() {
+//		const iterationCount = 1000
+//		const parallelCount = 100
+//
+//		makeBuffer := func() (*bytes.Buffer, error) {
+//			return bytes.NewBuffer(make([]byte, 0, bufSize)), nil
+//		}
+//
+//		resetBuffer := func(bb *bytes.Buffer) {
+//			bb.Reset()
+//		}
+//
+//		bp, err := typed.NewChan(typed.Size[*bytes.Buffer](poolSize), typed.Factory(makeBuffer), typed.Reset(resetBuffer))
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//
+//		var wg sync.WaitGroup
+//		wg.Add(parallelCount)
+//
+//		for i := 0; i < parallelCount; i++ {
+//			go func() {
+//				defer wg.Done()
+//
+//				for j := 0; j < iterationCount; j++ {
+//					if err := grabBufferAndUseIt(bp); err != nil {
+//						fmt.Println(err)
+//						return
+//					}
+//				}
+//			}()
+//		}
+//		wg.Wait()
+//	}
+//
+//	func grabBufferAndUseIt(pool typed.Pool[*bytes.Buffer]) error {
+//		// WARNING: Must ensure resource returns to pool otherwise gopool will deadlock once all
+//		// resources used.
+//		bb := pool.Get()
+//		defer pool.Put(bb) // IMPORTANT: defer here to ensure invoked even when subsequent code bails
+//
+//		for k := 0; k < bufSize; k++ {
+//			if rand.Intn(100000000) == 1 {
+//				return errors.New("random error to illustrate need to return resource to pool")
+//			}
+//			bb.WriteByte(byte(k % 256))
+//		}
+//		return nil
+//	}
+func NewChan[T any](setters ...Configurator[T]) (Pool[T], error) {
+	pc, err := newConfig(setters)
+	if err != nil {
+		return nil, err
+	}
+	pool := &ChanPool[T]{
+		ch:      make(chan entry[T], pc.size),
+		slots:   make(chan struct{}, pc.size),
+		room:    make(chan struct{}, pc.maxExtra),
+		done:    make(chan struct{}),
+		drained: make(chan struct{}),
+	}
+	pool.init(pc)
+	items, err := pool.fill(pool.pc.minIdle)
+	if err != nil {
+		pool.cancel()
+		return nil, err
+	}
+	for _, item := range items {
+		pool.slots <- struct{}{}
+		pool.give(pool.pc.idled(pool.pc.newEntry(item)))
+	}
+	if interval := pool.pc.reapInterval(); interval > 0 {
+		go pool.reap(interval)
+	}
+	return pool, nil
+}
+
+// Get acquires and returns an item from the pool of resources. Get blocks while there are no items in
+// the pool. Get returns the zero value of T when the pool is closed, or when the factory fails to
+// create a new item.
+func (pool *ChanPool[T]) Get() T {
+	item, _ := pool.GetContext(context.Background()) // background context is never canceled
+	return item
+}
+
+// GetContext acquires and returns an item from the pool of resources. When there are no items in
+// the pool, but the pool holds fewer than its maximum number of items, GetContext returns a new
+// item from the factory, passing ctx to a factory specified by FactoryContext. Otherwise GetContext
+// blocks while there are no items in the pool, or until the provided context is canceled or its
+// deadline expires. When the context is canceled, GetContext returns context.Canceled, and when its
+// deadline expires, GetContext returns an error for which errors.Is reports both ErrTimeout and
+// context.DeadlineExceeded. GetContext returns ErrClosed when the pool is closed, including while
+// blocked waiting for an item.
+func (pool *ChanPool[T]) GetContext(ctx context.Context) (T, error) {
+	return pool.get(ctx, true)
+}
+
+// TryGet acquires and returns an item from the pool of resources without blocking. When there are
+// no items in the pool, but the pool holds fewer than its maximum number of items, TryGet returns a
+// new item from the factory. TryGet returns false when it would otherwise block waiting for an
+// item, when the pool is closed, or when the factory fails to create a new item. TryGet calls the
+// factory at most once, rather than retrying failed calls according to FactoryRetry.
+func (pool *ChanPool[T]) TryGet() (T, bool) {
+	item, err := pool.get(context.Background(), false)
+	return item, err == nil
+}
+
+// get acquires and returns an item from the pool of resources. When wait is false, get returns
+// ErrExhausted rather than block waiting for an item.
+func (pool *ChanPool[T]) get(ctx context.Context, wait bool) (item T, err error) {
+	if pool.pc.hooks.OnGet != nil {
+		start := time.Now()
+		defer func() {
+			if err == nil {
+				pool.got(item, start)
+			}
+		}()
+	}
+	var zero T
+	if ctx.Err() != nil {
+		return zero, waitError(ctx)
+	}
+	for {
+		// Prefer an idle item, then creating a new item, before blocking to wait for either.
+		select {
+		case <-pool.done:
+			return zero, ErrClosed
+		case e := <-pool.ch:
+			e = pool.receive(e, false)
+			if !pool.usable(e) {
+				continue
+			}
+			return pool.checkout(e)
+		default:
+		}
+		select {
+		case pool.slots <- struct{}{}:
+			return pool.create(ctx, wait, false)
+		default:
+		}
+		if pool.overflow() {
+			return pool.create(ctx, wait, true)
+		}
+		if !wait {
+			return zero, ErrExhausted
+		}
+
+		start := pool.counters.waiting()
+		select {
+		case e := <-pool.ch:
+			pool.counters.waited(start)
+			e = pool.receive(e, false)
+			if !pool.usable(e) {
+				continue
+			}
+			return pool.checkout(e)
+		case pool.slots <- struct{}{}:
+			pool.counters.waited(start)
+			return pool.create(ctx, wait, false)
+		case <-pool.room:
+			pool.counters.waited(start) // try again to create a temporary item
+		case <-ctx.Done():
+			pool.counters.waited(start)
+			return zero, waitError(ctx)
+		case <-pool.done:
+			pool.counters.waited(start)
+			return zero, ErrClosed
+		}
+	}
+}
+
+// Acquire returns a Lease for an item from the pool of resources, blocking like Get. Acquire returns
+// ErrClosed when the pool is closed, or the error from the factory when it fails to create a new
+// item.
+func (pool *ChanPool[T]) Acquire() (*Lease[T], error) {
+	return pool.AcquireContext(context.Background()) // background context is never canceled
+}
+
+// AcquireContext returns a Lease for an item from the pool of resources, blocking like GetContext
+// until the provided context is canceled or its deadline expires.
+func (pool *ChanPool[T]) AcquireContext(ctx context.Context) (*Lease[T], error) {
+	return acquire[T](ctx, pool, &pool.counters, true)
+}
+
+// TryAcquire returns a Lease for an item from the pool of resources without blocking, like TryGet.
+// TryAcquire returns ErrExhausted when it would otherwise block waiting for an item.
+func (pool *ChanPool[T]) TryAcquire() (*Lease[T], error) {
+	return acquire[T](context.Background(), pool, &pool.counters, false)
+}
+
+// usable returns true when the item in e taken from the channel may be handed to a caller, and
+// otherwise discards the item.
+func (pool *ChanPool[T]) usable(e entry[T]) bool {
+	if e.expired() {
+		pool.discard(e.item, DiscardExpired)
+		return false
+	}
+	if reason, ok := pool.valid("ValidateOnGet", pool.pc.validateOnGet, e.item); !ok {
+		pool.discard(e.item, reason)
+		return false
+	}
+	return true
+}
+
+// checkout records the item in e taken from the channel as checked out, and returns it.
+func (pool *ChanPool[T]) checkout(e entry[T]) (T, error) {
+	var zero T
+	pool.lock.Lock()
+	if pool.closed {
+		// Close did not drain this item from the channel, so it must be closed here.
+		pool.lock.Unlock()
+		_ = pool.destroy(e.item, DiscardClosed)
+		return zero, ErrClosed
+	}
+	e.idleExpires = time.Time{}
+	pool.borrow(&e)
+	pool.ledger.add(e)
+	pool.replenish()
+	pool.lock.Unlock()
+	atomic.AddUint64(&pool.counters.gets, 1)
+	return e.item, nil
+}
+
+// overflow returns true after reserving a place for a temporary item beyond the pool's capacity,
+// when fewer than the maximum number of temporary items exist.
+func (pool *ChanPool[T]) overflow() bool {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.extra >= pool.pc.maxExtra {
+		return false
+	}
+	pool.extra++
+	return true
+}
+
+// freeExtra releases the place of a temporary item, waking a caller waiting to create one. It must
+// be called with the lock held.
+func (pool *ChanPool[T]) freeExtra() {
+	pool.extra--
+	select {
+	case pool.room <- struct{}{}:
+	default: // a caller already has a token to wake it
+	}
+}
+
+// create returns a new item from the factory, passing it ctx, for a caller that has already
+// reserved a place for it in the pool by sending a token to the slots channel, or when extra is
+// true, a temporary item for a caller that has reserved a place for it by calling overflow. When
+// wait is false, the factory is called only once.
+func (pool *ChanPool[T]) create(ctx context.Context, wait, extra bool) (T, error) {
+	var zero T
+	item, err := pool.produce(ctx, wait)
+	if err != nil {
+		if extra {
+			pool.lock.Lock()
+			pool.freeExtra()
+			pool.lock.Unlock()
+		} else {
+			<-pool.slots
+		}
+		return zero, err
+	}
+	pool.lock.Lock()
+	if pool.closed {
+		pool.lock.Unlock()
+		_ = pool.destroy(item, DiscardClosed)
+		return zero, ErrClosed
+	}
+	if extra {
+		atomic.AddUint64(&pool.counters.overflows, 1)
+	}
+	e := pool.pc.newEntry(item)
+	e.extra = extra
+	pool.borrow(&e)
+	pool.ledger.add(e)
+	pool.lock.Unlock()
+	atomic.AddUint64(&pool.counters.gets, 1)
+	return item, nil
+}
+
+// discard closes item, discarded for reason, and releases its place in the pool so a replacement
+// may be created. It returns the error from closing item.
+func (pool *ChanPool[T]) discard(item T, reason DiscardReason) error {
+	err := pool.destroy(item, reason)
+	<-pool.slots
+	pool.lock.Lock()
+	pool.replenish()
+	pool.lock.Unlock()
+	return err
+}
+
+// replenish starts creating items in the background when the pool has fewer than the minimum
+// number of idle items, and fewer than the maximum number of items. It must be called with the lock
+// held.
+func (pool *ChanPool[T]) replenish() {
+	if pool.replenishing || pool.closed || len(pool.ch) >= pool.pc.minIdle || len(pool.slots) >= pool.pc.size {
+		return
+	}
+	pool.replenishing = true
+	go func() {
+		// Checking whether more items are needed, and clearing replenishing when they are not,
+		// while holding the lock ensures a Get that takes an item meanwhile either is seen here,
+		// or starts replenishing again itself.
+		pool.lock.Lock()
+		for !pool.closed && len(pool.ch) < pool.pc.minIdle {
+			select {
+			case pool.slots <- struct{}{}:
+			default:
+				pool.replenishing = false
+				pool.lock.Unlock()
+				return // pool already has its maximum number of items
+			}
+			pool.lock.Unlock()
+			item, err := pool.produce(pool.ctx, true)
+			if err != nil {
+				<-pool.slots
+				pool.lock.Lock()
+				break // try again next time an item is taken from the pool
+			}
+			ok := pool.give(pool.pc.idled(pool.pc.newEntry(item)))
+			pool.lock.Lock()
+			if !ok {
+				break
+			}
+		}
+		pool.replenishing = false
+		pool.lock.Unlock()
+	}()
+}
+
+// reap periodically discards idle items that have expired, until the pool is closed.
+func (pool *ChanPool[T]) reap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.done:
+			return
+		case <-ticker.C:
+			if !pool.reapIdle() {
+				return
+			}
+		}
+	}
+}
+
+// reapIdle cycles through the items idle at this moment, discarding those that have expired, and
+// returning the others to the pool in the same order. It returns false when the pool is closed.
+func (pool *ChanPool[T]) reapIdle() bool {
+	for n := len(pool.ch); n > 0; n-- {
+		var e entry[T]
+		select {
+		case e = <-pool.ch:
+		default:
+			return true // remaining items taken by Get
+		}
+		if e = pool.receive(e, true); e.expired() {
+			pool.discard(e.item, DiscardExpired)
+		} else if !pool.give(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// give adds the item in e to the pool without blocking, returning false when the item could not be
+// added, in which case it has been closed.
+func (pool *ChanPool[T]) give(e entry[T]) bool {
+	pool.lock.Lock()
+	pool.ledger.park(e.item)
+	if pool.pc.order != FIFO {
+		pool.idle = append(pool.idle, e)
+		e = entry[T]{} // placeholder for the idle item
+	}
+	pool.lock.Unlock()
+	reason := DiscardFull
+	select {
+	case <-pool.done:
+		reason = DiscardClosed
+	case pool.ch <- e:
+		select {
+		case <-pool.done:
+			// Close might have drained the channel before the above send.
+			_ = pool.drain()
+			return false
+		default:
+			return true
+		}
+	default:
+	}
+	e = pool.receive(e, false) // no placeholder was sent for the idle item, so take one back
+	<-pool.slots
+	_ = pool.destroy(e.item, reason)
+	return false
+}
+
+// receive returns the entry of the idle item for e, which was received from the channel. When the
+// pool is FIFO, the channel holds the idle items themselves, and receive returns e. Otherwise the
+// channel holds a placeholder for each idle item, and receive removes and returns an idle item
+// chosen according to the pool's order, or the one idle the longest when oldest is true. Either
+// way, the item is no longer recorded as idle, unless the pool is closed, so releasing an item
+// after Close closed it is recognized as a double release.
+func (pool *ChanPool[T]) receive(e entry[T], oldest bool) entry[T] {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if pool.pc.order != FIFO {
+		order := pool.pc.order
+		if oldest {
+			order = FIFO
+		}
+		e = takeIdle(&pool.idle, order)
+	}
+	if !pool.closed {
+		pool.ledger.unpark(e.item)
+	}
+	return e
+}
+
+// Put will release a resource back to the pool. Put never blocks. If the Pool was initialized with
+// a Reset function, it will be invoked with the resource as its sole argument, prior to the resource
+// being added back to the pool. If Put is called when adding the resource to the pool _would_
+// result in having more elements in the pool than the pool size, for instance when the resource
+// was not checked out of the pool, or is a temporary item created by Overflow, the resource is
+// effectively dropped on the floor after calling any optional Reset and Close methods on the
+// resource. When the pool has been closed, the resource has exceeded its maximum lifetime, or the
+// resource fails validation, the resource is passed to any optional Close function rather than
+// being added back to the pool. Releasing a resource that is already idle in the pool, or that the
+// pool closed when it was closed, for instance by calling Put twice for the same resource, has no
+// effect other than being counted in the pool's Stats as a double release.
+func (pool *ChanPool[T]) Put(item T) {
+	_ = pool.put(item)
+}
+
+// TryPut releases a resource back to the pool like Put, returning true when the resource was added
+// back to the pool, and false when it was instead passed to the optional Close function, for
+// instance because the pool was already full.
+func (pool *ChanPool[T]) TryPut(item T) bool {
+	return pool.put(item)
+}
+
+// put releases item back to the pool, returning true when item was added back to the pool.
+func (pool *ChanPool[T]) put(item T) bool {
+	atomic.AddUint64(&pool.counters.puts, 1)
+	reason, ok := pool.recycle(item)
+
+	pool.lock.Lock()
+	e, known := pool.ledger.remove(item)
+	if !known && pool.ledger.idle(item) {
+		pool.lock.Unlock()
+		atomic.AddUint64(&pool.counters.doubleReleases, 1)
+		return false
+	}
+	defer pool.returned(item, e)
+	if known && e.extra {
+		pool.freeExtra()
+	}
+	if pool.closed {
+		if known && pool.ledger.len() == 0 {
+			pool.signalDrained()
+		}
+		pool.lock.Unlock()
+		_ = pool.destroy(item, DiscardClosed)
+		return false
+	}
+	pool.lock.Unlock()
+
+	if !ok {
+		if known && !e.extra {
+			_ = pool.discard(item, reason)
+		} else {
+			_ = pool.destroy(item, reason)
+		}
+		return false
+	}
+	if !known || e.extra {
+		// Keep a foreign or temporary item only when the pool has room for it.
+		select {
+		case pool.slots <- struct{}{}:
+		default:
+			_ = pool.destroy(item, DiscardFull)
+			return false
+		}
+		if !known {
+			e = pool.pc.newEntry(item)
+		}
+		e.extra = false
+	}
+	if e.expired() {
+		_ = pool.discard(item, DiscardExpired)
+		return false
+	}
+	return pool.give(pool.pc.idled(e))
+}
+
+// Discard passes a checked out resource to the optional close function rather than releasing it
+// back to the pool, for instance when the resource is broken, and returns the error from closing
+// it. The resource's place in the pool is freed, so the pool does not shrink: a pool filled during
+// initialization creates a replacement in the background right away, and a pool that creates items
+// on demand does so when a caller next needs one, or to keep MinIdle items idle. Discard returns
+// ErrNotCheckedOut without closing a resource that is not checked out of the pool, for instance
+// because it has already been released or discarded, and counts it in the pool's Stats as a double
+// release. After the pool is closed, Discard closes the resource just like Put.
+func (pool *ChanPool[T]) Discard(item T) error {
+	pool.lock.Lock()
+	e, ok := pool.ledger.remove(item)
+	if !ok {
+		pool.lock.Unlock()
+		atomic.AddUint64(&pool.counters.doubleReleases, 1)
+		return ErrNotCheckedOut
+	}
+	if e.extra {
+		pool.freeExtra()
+	}
+	if pool.closed {
+		if pool.ledger.len() == 0 {
+			pool.signalDrained()
+		}
+		pool.lock.Unlock()
+		return pool.destroy(item, DiscardClosed)
+	}
+	pool.lock.Unlock()
+
+	if e.extra {
+		return pool.destroy(item, DiscardCaller) // item has no place in the pool to release
+	}
+	return pool.discard(item, DiscardCaller)
+}
+
+// Outstanding returns the items checked out of the pool for longer than threshold, longest held
+// first, along with when and where each was checked out. Outstanding returns nil unless the pool
+// tracks borrowers.
+func (pool *ChanPool[T]) Outstanding(threshold time.Duration) []Borrow[T] {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	return outstanding(&pool.ledger, threshold)
+}
+
+// Stats returns a description of the pool.
+func (pool *ChanPool[T]) Stats() Stats {
+	st := pool.stats()
+	st.Idle = len(pool.ch)
+	pool.lock.Lock()
+	st.InUse = pool.ledger.len()
+	st.Extra = pool.extra
+	pool.lock.Unlock()
+	return st
+}
+
+// Close is called when the Pool is no longer needed, and the resources in the Pool ought to be
+// released.  If a Pool has a close function, it will be invoked one time for each resource, with
+// that resource as its sole argument, and Close returns a *CloseError when it returns an error for
+// any resource. Callers blocked in Get or GetContext are woken, and resources later released by Put
+// are passed to the close function.
+func (pool *ChanPool[T]) Close() error {
+	pool.lock.Lock()
+	first := !pool.closed
+	if first {
+		pool.closed = true
+		close(pool.done)
+		pool.cancel()
+		if pool.ledger.len() == 0 {
+			pool.signalDrained()
+		}
+	}
+	pool.lock.Unlock()
+	err := pool.drain()
+	if first {
+		pool.poolClosed(err)
+	}
+	return err
+}
+
+// Shutdown closes the Pool like Close, then waits for every checked out resource to be released
+// back to the pool by Put, which passes each to the optional close function as it is returned. If
+// ctx is done before every resource has been returned, Shutdown passes each remaining checked out
+// resource to the optional close function, and returns an *OutstandingError listing those
+// resources. In that case, errors from closing resources are not reported.
+func (pool *ChanPool[T]) Shutdown(ctx context.Context) error {
+	err := pool.Close()
+
+	select {
+	case <-pool.drained:
+		return err
+	case <-ctx.Done():
+	}
+
+	pool.lock.Lock()
+	if pool.ledger.len() == 0 {
+		pool.lock.Unlock()
+		return err
+	}
+	outstanding := pool.ledger.drain()
+	pool.signalDrained()
+	pool.lock.Unlock()
+
+	for _, item := range outstanding {
+		_ = pool.destroy(item, DiscardClosed)
+	}
+	return &OutstandingError[T]{Err: ctx.Err(), Items: outstanding}
+}
+
+// signalDrained wakes all callers of Shutdown. It must be called with the lock held.
+func (pool *ChanPool[T]) signalDrained() {
+	if !pool.drainedClosed {
+		pool.drainedClosed = true
+		close(pool.drained)
+	}
+}
+
+// drain closes each item remaining in the channel, returning a *CloseError when closing any of them
+// returns an error.
+func (pool *ChanPool[T]) drain() error {
+	var items []T
+	for {
+		select {
+		case e := <-pool.ch:
+			items = append(items, pool.receive(e, true).item)
+		default:
+			return pool.closeEach(items, DiscardClosed)
+		}
+	}
+}
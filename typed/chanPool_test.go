@@ -0,0 +1,23 @@
+package typed_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/karrick/gopool/typed"
+)
+
+func BenchmarkChanLowConcurrency(b *testing.B) {
+	pool, _ := typed.NewChan(typed.Factory(makeBuffer), typed.Reset(resetBuffer), typed.Close(closeBuffer), typed.Size[*bytes.Buffer](lowCap))
+	bench(b, pool, lowConcurrency)
+}
+
+func BenchmarkChanMediumConcurrency(b *testing.B) {
+	pool, _ := typed.NewChan(typed.Factory(makeBuffer), typed.Reset(resetBuffer), typed.Close(closeBuffer), typed.Size[*bytes.Buffer](medCap))
+	bench(b, pool, medConcurrency)
+}
+
+func BenchmarkChanHighConcurrency(b *testing.B) {
+	pool, _ := typed.NewChan(typed.Factory(makeBuffer), typed.Reset(resetBuffer), typed.Close(closeBuffer), typed.Size[*bytes.Buffer](largeCap))
+	bench(b, pool, highConcurrency)
+}
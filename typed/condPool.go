@@ -0,0 +1,543 @@
+package typed
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// condPool holds the logic shared by ArrayPool and SemaphorePool, which protect their idle items
+// with a mutex, and block callers waiting for an item on a condition variable. The pools differ
+// only in how they hold their idle items, and whether they wake every blocked caller or a single
+// one when an item becomes available.
+type condPool[T any] struct {
+	base[T]
+
+	lock         sync.Mutex    // protects the following fields
+	getc         *sync.Cond    // signaled when a blocked Get may be able to proceed
+	putc         *sync.Cond    // broadcast when a checked out item is released after Close
+	wakeOne      bool          // true to signal a single blocked Get rather than broadcast to all of them
+	done         chan struct{} // closed when the pool is closed
+	closed       bool
+	replenishing bool
+	ledger       ledger[T]
+	total        int // number of items, both idle and checked out, including those being created
+	extra        int // number of temporary items created by Overflow, including those being created
+	idle         idleStore[T]
+}
+
+// idleStore holds the idle entries of a condPool. Its methods must be called with the pool's lock
+// held.
+type idleStore[T any] interface {
+	// len returns the number of idle entries.
+	len() int
+
+	// push adds e as the entry released most recently. It is never called when the pool already
+	// holds its maximum number of items.
+	push(e entry[T])
+
+	// take removes and returns an idle entry chosen according to order, where FIFO chooses the
+	// entry idle the longest. It is only called when there is an idle entry.
+	take(order Ordering) entry[T]
+}
+
+// start prepares the pool for use with the configuration pc, keeping its idle entries in idle, and
+// fills it. When wakeOne is true, callers blocked waiting for an item are woken one at a time,
+// rather than all at once, as items become available.
+func (pool *condPool[T]) start(pc *config[T], idle idleStore[T], wakeOne bool) error {
+	pool.getc = sync.NewCond(&pool.lock)
+	pool.putc = sync.NewCond(&pool.lock)
+	pool.wakeOne = wakeOne
+	pool.done = make(chan struct{})
+	pool.idle = idle
+	pool.init(pc)
+	items, err := pool.fill(pool.pc.minIdle)
+	if err != nil {
+		pool.cancel()
+		return err
+	}
+	for _, item := range items {
+		pool.push(pool.pc.idled(pool.pc.newEntry(item)))
+		pool.total++
+	}
+	if interval := pool.pc.reapInterval(); interval > 0 {
+		go pool.reap(interval)
+	}
+	return nil
+}
+
+// wake wakes the callers blocked in Get that may now be able to proceed, either because an item
+// was released, or because there is room to create one.
+func (pool *condPool[T]) wake() {
+	if pool.wakeOne {
+		pool.getc.Signal()
+	} else {
+		pool.getc.Broadcast()
+	}
+}
+
+// Get acquires and returns an item from the pool of resources. Get blocks while there are no items in
+// the pool. Get returns the zero value of T when the pool is closed, or when the factory fails to
+// create a new item.
+func (pool *condPool[T]) Get() T {
+	item, _ := pool.GetContext(context.Background()) // background context is never canceled
+	return item
+}
+
+// GetContext acquires and returns an item from the pool of resources. When there are no items in
+// the pool, but the pool holds fewer than its maximum number of items, GetContext returns a new
+// item from the factory, passing ctx to a factory specified by FactoryContext. Otherwise GetContext
+// blocks while there are no items in the pool, or until the provided context is canceled or its
+// deadline expires. When the context is canceled, GetContext returns context.Canceled, and when its
+// deadline expires, GetContext returns an error for which errors.Is reports both ErrTimeout and
+// context.DeadlineExceeded. GetContext returns ErrClosed when the pool is closed, including while
+// blocked waiting for an item.
+func (pool *condPool[T]) GetContext(ctx context.Context) (T, error) {
+	return pool.get(ctx, true)
+}
+
+// TryGet acquires and returns an item from the pool of resources without blocking. When there are
+// no items in the pool, but the pool holds fewer than its maximum number of items, TryGet returns a
+// new item from the factory. TryGet returns false when it would otherwise block waiting for an
+// item, when the pool is closed, or when the factory fails to create a new item. TryGet calls the
+// factory at most once, rather than retrying failed calls according to FactoryRetry.
+func (pool *condPool[T]) TryGet() (T, bool) {
+	item, err := pool.get(context.Background(), false)
+	return item, err == nil
+}
+
+// get acquires and returns an item from the pool of resources. When wait is false, get returns
+// ErrExhausted rather than block waiting for an item.
+func (pool *condPool[T]) get(ctx context.Context, wait bool) (item T, err error) {
+	if pool.pc.hooks.OnGet != nil {
+		start := time.Now()
+		defer func() {
+			if err == nil {
+				pool.got(item, start)
+			}
+		}()
+	}
+	var zero T
+	if ctx.Err() != nil {
+		return zero, waitError(ctx)
+	}
+
+	pool.lock.Lock()
+	var waiting bool
+	for {
+		if pool.closed {
+			pool.lock.Unlock()
+			return zero, ErrClosed
+		}
+		if pool.idle.len() > 0 {
+			e := pool.take(pool.pc.order)
+			if e.expired() {
+				pool.discard(e.item, DiscardExpired)
+				continue
+			}
+			if pool.pc.validateOnGet != nil {
+				// validate without holding the lock
+				pool.lock.Unlock()
+				reason, ok := pool.valid("ValidateOnGet", pool.pc.validateOnGet, e.item)
+				pool.lock.Lock()
+				if !ok {
+					pool.discard(e.item, reason)
+					continue
+				}
+				if pool.closed {
+					pool.total--
+					pool.lock.Unlock()
+					_ = pool.destroy(e.item, DiscardClosed)
+					return zero, ErrClosed
+				}
+			}
+			e.idleExpires = time.Time{}
+			pool.borrow(&e)
+			pool.ledger.add(e)
+			pool.replenish()
+			pool.lock.Unlock()
+			atomic.AddUint64(&pool.counters.gets, 1)
+			return e.item, nil
+		}
+		if pool.total < pool.pc.size {
+			pool.total++
+			pool.lock.Unlock()
+			return pool.create(ctx, wait, false)
+		}
+		if pool.extra < pool.pc.maxExtra {
+			pool.extra++
+			pool.lock.Unlock()
+			return pool.create(ctx, wait, true)
+		}
+		if !wait {
+			pool.lock.Unlock()
+			return zero, ErrExhausted
+		}
+		// Checking the context while holding the lock ensures an item is either taken by this
+		// goroutine or left in the pool for another waiter, and never lost. A waiter woken to take
+		// an item always takes it when one remains, even when its context is done, so a signal is
+		// never wasted on a waiter that bails out.
+		if ctx.Err() != nil {
+			pool.lock.Unlock()
+			return zero, waitError(ctx)
+		}
+		if !waiting {
+			waiting = true
+			defer pool.counters.waited(pool.counters.waiting())
+			defer broadcastWhenDone(ctx, pool.getc)()
+		}
+		pool.getc.Wait()
+	}
+}
+
+// Acquire returns a Lease for an item from the pool of resources, blocking like Get. Acquire returns
+// ErrClosed when the pool is closed, or the error from the factory when it fails to create a new
+// item.
+func (pool *condPool[T]) Acquire() (*Lease[T], error) {
+	return pool.AcquireContext(context.Background()) // background context is never canceled
+}
+
+// AcquireContext returns a Lease for an item from the pool of resources, blocking like GetContext
+// until the provided context is canceled or its deadline expires.
+func (pool *condPool[T]) AcquireContext(ctx context.Context) (*Lease[T], error) {
+	return acquire[T](ctx, pool, &pool.counters, true)
+}
+
+// TryAcquire returns a Lease for an item from the pool of resources without blocking, like TryGet.
+// TryAcquire returns ErrExhausted when it would otherwise block waiting for an item.
+func (pool *condPool[T]) TryAcquire() (*Lease[T], error) {
+	return acquire[T](context.Background(), pool, &pool.counters, false)
+}
+
+// create returns a new item from the factory, passing it ctx, for a caller that has already
+// reserved a place for it in the pool by incrementing total, or when extra is true, a temporary
+// item for a caller that has reserved a place for it by incrementing extra. When wait is false, the
+// factory is called only once.
+func (pool *condPool[T]) create(ctx context.Context, wait, extra bool) (T, error) {
+	var zero T
+	item, err := pool.produce(ctx, wait)
+
+	pool.lock.Lock()
+	if err != nil || pool.closed {
+		if extra {
+			pool.extra--
+		} else {
+			pool.total--
+		}
+	}
+	if err != nil {
+		pool.lock.Unlock()
+		pool.wake() // another waiter may now create an item
+		return zero, err
+	}
+	if pool.closed {
+		pool.lock.Unlock()
+		_ = pool.destroy(item, DiscardClosed)
+		return zero, ErrClosed
+	}
+	if extra {
+		atomic.AddUint64(&pool.counters.overflows, 1)
+	}
+	e := pool.pc.newEntry(item)
+	e.extra = extra
+	pool.borrow(&e)
+	pool.ledger.add(e)
+	pool.lock.Unlock()
+	atomic.AddUint64(&pool.counters.gets, 1)
+	return item, nil
+}
+
+// replenish starts creating items in the background when the pool has fewer than the minimum
+// number of idle items, and fewer than the maximum number of items. It must be called with the lock
+// held.
+func (pool *condPool[T]) replenish() {
+	if pool.replenishing || pool.closed || pool.idle.len() >= pool.pc.minIdle || pool.total >= pool.pc.size {
+		return
+	}
+	pool.replenishing = true
+	go func() {
+		pool.lock.Lock()
+		for !pool.closed && pool.idle.len() < pool.pc.minIdle && pool.total < pool.pc.size {
+			pool.total++
+			pool.lock.Unlock()
+			item, err := pool.produce(pool.ctx, true)
+			pool.lock.Lock()
+			if err != nil {
+				pool.total--
+				break // try again next time an item is taken from the pool
+			}
+			if pool.closed {
+				pool.total--
+				pool.lock.Unlock()
+				_ = pool.destroy(item, DiscardClosed)
+				pool.lock.Lock()
+				break
+			}
+			pool.push(pool.pc.idled(pool.pc.newEntry(item)))
+			pool.wake()
+		}
+		pool.replenishing = false
+		pool.lock.Unlock()
+	}()
+}
+
+// Put will release a resource back to the pool. Put never blocks. If the Pool was initialized with
+// a Reset function, it will be invoked with the resource as its sole argument, prior to the resource
+// being added back to the pool. If Put is called when adding the resource to the pool _would_
+// result in having more elements in the pool than the pool size, for instance when the resource
+// was not checked out of the pool, or is a temporary item created by Overflow, the resource is
+// effectively dropped on the floor after calling any optional Reset and Close methods on the
+// resource. When the pool has been closed, the resource has exceeded its maximum lifetime, or the
+// resource fails validation, the resource is passed to any optional Close function rather than
+// being added back to the pool. Releasing a resource that is already idle in the pool, or that the
+// pool closed when it was closed, for instance by calling Put twice for the same resource, has no
+// effect other than being counted in the pool's Stats as a double release.
+func (pool *condPool[T]) Put(item T) {
+	_ = pool.put(item)
+}
+
+// TryPut releases a resource back to the pool like Put, returning true when the resource was added
+// back to the pool, and false when it was instead passed to the optional Close function, for
+// instance because the pool was already full.
+func (pool *condPool[T]) TryPut(item T) bool {
+	return pool.put(item)
+}
+
+// put releases item back to the pool, returning true when item was added back to the pool.
+func (pool *condPool[T]) put(item T) bool {
+	atomic.AddUint64(&pool.counters.puts, 1)
+	reason, ok := pool.recycle(item)
+
+	pool.lock.Lock()
+	e, known := pool.ledger.remove(item)
+	if !known && pool.ledger.idle(item) {
+		pool.lock.Unlock()
+		atomic.AddUint64(&pool.counters.doubleReleases, 1)
+		return false
+	}
+	defer pool.returned(item, e)
+	if known && e.extra {
+		pool.extra--
+	}
+	if pool.closed {
+		if known && !e.extra {
+			pool.total--
+		}
+		pool.lock.Unlock()
+		pool.putc.Broadcast() // wake Shutdown
+		_ = pool.destroy(item, DiscardClosed)
+		return false
+	}
+	if !ok {
+		if known && !e.extra {
+			_ = pool.discard(item, reason)
+			pool.lock.Unlock()
+		} else {
+			pool.lock.Unlock()
+			pool.wake() // another waiter may now create a temporary item
+			_ = pool.destroy(item, reason)
+		}
+		return false
+	}
+	if !known || e.extra {
+		// Keep a foreign or temporary item only when the pool has room for it.
+		if pool.total >= pool.pc.size {
+			pool.lock.Unlock()
+			pool.wake() // another waiter may now create a temporary item
+			_ = pool.destroy(item, DiscardFull)
+			return false
+		}
+		pool.total++
+		if !known {
+			e = pool.pc.newEntry(item)
+		}
+		e.extra = false
+	}
+	if e.expired() {
+		_ = pool.discard(item, DiscardExpired)
+		pool.lock.Unlock()
+		return false
+	}
+	pool.push(pool.pc.idled(e)) // never full, because total counts every idle item
+
+	pool.lock.Unlock()
+	pool.wake()
+	return true
+}
+
+// discard closes item, discarded for reason, and releases its place in the pool so a replacement
+// may be created. It must be called with the lock held, and releases the lock while closing the
+// item. It returns the error from closing item.
+func (pool *condPool[T]) discard(item T, reason DiscardReason) error {
+	pool.total--
+	pool.replenish()
+	pool.lock.Unlock()
+	pool.wake() // another waiter may now create an item
+	err := pool.destroy(item, reason)
+	pool.lock.Lock()
+	return err
+}
+
+// Discard passes a checked out resource to the optional close function rather than releasing it
+// back to the pool, for instance when the resource is broken, and returns the error from closing
+// it. The resource's place in the pool is freed, so the pool does not shrink: a pool filled during
+// initialization creates a replacement in the background right away, and a pool that creates items
+// on demand does so when a caller next needs one, or to keep MinIdle items idle. Discard returns
+// ErrNotCheckedOut without closing a resource that is not checked out of the pool, for instance
+// because it has already been released or discarded, and counts it in the pool's Stats as a double
+// release. After the pool is closed, Discard closes the resource just like Put.
+func (pool *condPool[T]) Discard(item T) error {
+	pool.lock.Lock()
+	e, ok := pool.ledger.remove(item)
+	if !ok {
+		pool.lock.Unlock()
+		atomic.AddUint64(&pool.counters.doubleReleases, 1)
+		return ErrNotCheckedOut
+	}
+	if e.extra {
+		pool.extra--
+	}
+	if pool.closed {
+		if !e.extra {
+			pool.total--
+		}
+		pool.lock.Unlock()
+		pool.putc.Broadcast() // wake Shutdown
+		return pool.destroy(item, DiscardClosed)
+	}
+	if e.extra {
+		pool.lock.Unlock()
+		pool.wake()                              // another waiter may now create a temporary item
+		return pool.destroy(item, DiscardCaller) // item has no place in the pool to release
+	}
+	err := pool.discard(item, DiscardCaller)
+	pool.lock.Unlock()
+	return err
+}
+
+// Outstanding returns the items checked out of the pool for longer than threshold, longest held
+// first, along with when and where each was checked out. Outstanding returns nil unless the pool
+// tracks borrowers.
+func (pool *condPool[T]) Outstanding(threshold time.Duration) []Borrow[T] {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	return outstanding(&pool.ledger, threshold)
+}
+
+// Stats returns a description of the pool.
+func (pool *condPool[T]) Stats() Stats {
+	st := pool.stats()
+	pool.lock.Lock()
+	st.Idle = pool.idle.len()
+	st.InUse = pool.ledger.len()
+	st.Extra = pool.extra
+	pool.lock.Unlock()
+	return st
+}
+
+// reap periodically discards idle items that have expired, until the pool is closed.
+func (pool *condPool[T]) reap(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.done:
+			return
+		case <-ticker.C:
+		}
+
+		// Cycle through the idle items, discarding those that have expired, and returning the
+		// others to the pool in the same order.
+		var expired []T
+		pool.lock.Lock()
+		for n := pool.idle.len(); n > 0; n-- {
+			if e := pool.take(FIFO); e.expired() {
+				expired = append(expired, e.item)
+			} else {
+				pool.push(e)
+			}
+		}
+		pool.total -= len(expired)
+		pool.replenish()
+		pool.lock.Unlock()
+		pool.getc.Broadcast() // waiters may now create items
+
+		for _, item := range expired {
+			_ = pool.destroy(item, DiscardExpired)
+		}
+	}
+}
+
+// push adds e to the idle entries. It must be called with the lock held.
+func (pool *condPool[T]) push(e entry[T]) {
+	pool.ledger.park(e.item)
+	pool.idle.push(e)
+}
+
+// take removes and returns an idle entry chosen according to order. It must be called with the lock
+// held, and only when there is an idle entry. Once the pool is closed, the ledger keeps recording
+// the item as idle, so releasing it after Close closed it is recognized as a double release.
+func (pool *condPool[T]) take(order Ordering) entry[T] {
+	e := pool.idle.take(order)
+	if !pool.closed {
+		pool.ledger.unpark(e.item)
+	}
+	return e
+}
+
+// Close is called when the Pool is no longer needed, and the resources in the Pool ought to be
+// released.  If a Pool has a close function, it will be invoked one time for each resource, with
+// that resource as its sole argument, and Close returns a *CloseError when it returns an error for
+// any resource. Callers blocked in Get or GetContext are woken, and resources later released by Put
+// are passed to the close function.
+func (pool *condPool[T]) Close() error {
+	pool.lock.Lock()
+	if pool.closed {
+		pool.lock.Unlock()
+		return nil
+	}
+	pool.closed = true
+	close(pool.done)
+	pool.cancel()
+
+	idle := make([]T, 0, pool.idle.len())
+	for pool.idle.len() > 0 {
+		idle = append(idle, pool.take(FIFO).item)
+	}
+
+	pool.lock.Unlock()
+	pool.getc.Broadcast() // wake blocked Get calls so they observe closed pool
+
+	err := pool.closeEach(idle, DiscardClosed)
+	pool.poolClosed(err)
+	return err
+}
+
+// Shutdown closes the Pool like Close, then waits for every checked out resource to be released
+// back to the pool by Put, which passes each to the optional close function as it is returned. If
+// ctx is done before every resource has been returned, Shutdown passes each remaining checked out
+// resource to the optional close function, and returns an *OutstandingError listing those
+// resources. In that case, errors from closing resources are not reported.
+func (pool *condPool[T]) Shutdown(ctx context.Context) error {
+	err := pool.Close()
+
+	pool.lock.Lock()
+	if pool.ledger.len() > 0 {
+		defer broadcastWhenDone(ctx, pool.putc)()
+	}
+	for pool.ledger.len() > 0 && ctx.Err() == nil {
+		pool.putc.Wait()
+	}
+	if pool.ledger.len() == 0 {
+		pool.lock.Unlock()
+		return err
+	}
+	outstanding := pool.ledger.drain()
+	pool.lock.Unlock()
+
+	for _, item := range outstanding {
+		_ = pool.destroy(item, DiscardClosed)
+	}
+	return &OutstandingError[T]{Err: ctx.Err(), Items: outstanding}
+}
@@ -0,0 +1,69 @@
+package typed
+
+import (
+	"math/rand"
+	"time"
+)
+
+// entry is an item along with the times after which the pool ought to close it rather than hand it
+// out again. A zero time means the item does not expire for that reason.
+type entry[T any] struct {
+	item        T
+	expires     time.Time // when item exceeds its maximum lifetime
+	idleExpires time.Time // when item exceeds its maximum idle time, only while idle in the pool
+	borrowed    time.Time // when item was checked out, only while checked out of a pool that tracks borrowers or has an OnPut hook
+	stack       []uintptr // call stack that checked out item, only while checked out of a pool that tracks borrowers
+	extra       bool      // true for a temporary item created beyond the pool's capacity by Overflow
+}
+
+// expired returns true when the item has exceeded either its maximum lifetime or maximum idle time.
+func (e *entry[T]) expired() bool {
+	if e.expires.IsZero() && e.idleExpires.IsZero() {
+		return false
+	}
+	now := time.Now()
+	return (!e.expires.IsZero() && !now.Before(e.expires)) || (!e.idleExpires.IsZero() && !now.Before(e.idleExpires))
+}
+
+// newEntry returns an entry for a newly created item.
+func (pc *config[T]) newEntry(item T) entry[T] {
+	e := entry[T]{item: item}
+	if pc.maxLifetime > 0 {
+		e.expires = time.Now().Add(pc.maxLifetime - pc.jitter())
+	}
+	return e
+}
+
+// idled returns e updated for being returned to the pool.
+func (pc *config[T]) idled(e entry[T]) entry[T] {
+	e.borrowed = time.Time{}
+	e.stack = nil
+	if pc.maxIdleTime > 0 {
+		e.idleExpires = time.Now().Add(pc.maxIdleTime - pc.jitter())
+	}
+	return e
+}
+
+// jitter returns a random duration less than the configured expiry jitter.
+func (pc *config[T]) jitter() time.Duration {
+	if pc.expiryJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(pc.expiryJitter)))
+}
+
+// reapInterval returns how often a pool ought to look for expired idle items, or zero when items do
+// not expire.
+func (pc *config[T]) reapInterval() time.Duration {
+	d := pc.maxIdleTime
+	if pc.maxLifetime > 0 && (d == 0 || pc.maxLifetime < d) {
+		d = pc.maxLifetime
+	}
+	if d == 0 {
+		return 0
+	}
+	if d /= 2; d < time.Millisecond {
+		d = time.Millisecond
+	}
+	return d
+}
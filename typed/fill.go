@@ -0,0 +1,119 @@
+package typed
+
+import (
+	"context"
+	"sync"
+)
+
+// fillResult is the outcome of one factory call made while filling a pool.
+type fillResult[T any] struct {
+	index int
+	item  T
+	err   error
+}
+
+// fill returns count new items from the factory, making up to the configured fill concurrency
+// factory calls at once. When the factory fails, fill waits for the outstanding factory calls, passes
+// every item created to the close function, and returns a *FillError. When the fill timeout elapses
+// first, fill stops calling the factory, passes the items already created to the close function, and
+// returns a *FillError that wraps ErrTimeout. The items from factory calls still outstanding at that
+// time are passed to the close function as those calls return.
+func (b *base[T]) fill(count int) ([]T, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	ctx := b.ctx
+	if b.pc.fillTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.pc.fillTimeout)
+		defer cancel()
+	}
+
+	next := make(chan int)
+	stop := make(chan struct{}) // closed to stop calling the factory
+	var once sync.Once
+	halt := func() { once.Do(func() { close(stop) }) }
+	results := make(chan fillResult[T], count) // buffered so workers never block after fill returns
+
+	go func() {
+		defer close(next)
+		for i := 0; i < count; i++ {
+			select {
+			case next <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	workers := b.pc.fillConcurrency
+	if workers > count {
+		workers = count
+	}
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				item, err := b.produce(ctx, true)
+				if err != nil {
+					halt()
+				}
+				results <- fillResult[T]{index: i, item: item, err: err}
+			}
+		}()
+	}
+
+	items := make([]T, 0, count)
+	created := make([]bool, count)
+	var fe *FillError
+	for fe == nil && len(items) < count {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				fe = &FillError{Index: r.index, Err: r.err}
+				break
+			}
+			items = append(items, r.item)
+			created[r.index] = true
+		case <-ctx.Done():
+			fe = &FillError{Err: waitError(ctx)}
+			for created[fe.Index] {
+				fe.Index++
+			}
+		}
+	}
+	if fe == nil {
+		return items, nil
+	}
+	halt()
+
+	if ctx.Err() == nil {
+		// Outstanding factory calls are expected to return, so wait to close their items too.
+		wg.Wait()
+		close(results)
+		for r := range results {
+			if r.err == nil {
+				items = append(items, r.item)
+			}
+		}
+	} else {
+		go func() {
+			wg.Wait()
+			close(results)
+			for r := range results {
+				if r.err == nil {
+					_ = b.destroy(r.item, DiscardClosed)
+				}
+			}
+		}()
+	}
+	fe.CloseErr = b.closeEach(items, DiscardClosed)
+	return nil, fe
+}
@@ -0,0 +1,89 @@
+package typed
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// HistogramBuckets is the number of buckets in a Histogram.
+const HistogramBuckets = 25
+
+// Histogram counts durations in buckets with fixed bounds. The first bucket counts durations no
+// longer than one microsecond, and each following bucket counts durations no longer than twice the
+// bound of the previous bucket, up to the final bucket, which counts all longer durations.
+type Histogram struct {
+	Counts [HistogramBuckets]uint64
+}
+
+// Bound returns the inclusive upper bound of bucket i. Because the final bucket counts all durations
+// longer than the bound of the previous bucket, Bound returns the maximum duration for it.
+func (Histogram) Bound(i int) time.Duration {
+	if i >= HistogramBuckets-1 {
+		return math.MaxInt64
+	}
+	return time.Microsecond << uint(i)
+}
+
+// Count returns the number of durations counted by the histogram.
+func (h Histogram) Count() uint64 {
+	var count uint64
+	for _, n := range h.Counts {
+		count += n
+	}
+	return count
+}
+
+// Quantile returns the upper bound of the bucket that contains the q quantile of the counted
+// durations, for q between 0 and 1. For instance, Quantile(0.99) returns a duration that at least
+// 99 percent of the counted durations do not exceed. Quantile returns 0 when the histogram is empty.
+func (h Histogram) Quantile(q float64) time.Duration {
+	count := h.Count()
+	if count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(count)))
+	if rank == 0 {
+		rank = 1
+	}
+	var cumulative uint64
+	for i, n := range h.Counts {
+		if cumulative += n; cumulative >= rank {
+			return h.Bound(i)
+		}
+	}
+	return h.Bound(HistogramBuckets - 1)
+}
+
+// histogram is a Histogram whose counts are updated atomically, so durations may be recorded
+// without holding any pool lock.
+type histogram struct {
+	counts [HistogramBuckets]uint64
+}
+
+// record counts d in the bucket whose bounds include it.
+func (h *histogram) record(d time.Duration) {
+	atomic.AddUint64(&h.counts[bucket(d)], 1)
+}
+
+// snapshot returns a Histogram with the current counts.
+func (h *histogram) snapshot() Histogram {
+	var s Histogram
+	for i := range h.counts {
+		s.Counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return s
+}
+
+// bucket returns the index of the bucket whose bounds include d.
+func bucket(d time.Duration) int {
+	if d <= time.Microsecond {
+		return 0
+	}
+	us := uint64((d + time.Microsecond - 1) / time.Microsecond) // round up to whole microseconds
+	if i := bits.Len64(us - 1); i < HistogramBuckets {
+		return i
+	}
+	return HistogramBuckets - 1
+}
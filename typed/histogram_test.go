@@ -0,0 +1,49 @@
+package typed_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/karrick/gopool/typed"
+)
+
+func TestHistogramBound(t *testing.T) {
+	var h typed.Histogram
+	if actual, expected := h.Bound(0), time.Microsecond; actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+	if actual, expected := h.Bound(10), 1024*time.Microsecond; actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+	if actual, expected := h.Bound(typed.HistogramBuckets-1), time.Duration(math.MaxInt64); actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	var h typed.Histogram
+	if actual, expected := h.Quantile(0.99), time.Duration(0); actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+
+	h.Counts[0] = 900
+	h.Counts[3] = 90
+	h.Counts[10] = 9
+	h.Counts[20] = 1
+	if actual, expected := h.Count(), uint64(1000); actual != expected {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+	if actual, expected := h.Quantile(0.5), h.Bound(0); actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+	if actual, expected := h.Quantile(0.95), h.Bound(3); actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+	if actual, expected := h.Quantile(0.999), h.Bound(10); actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+	if actual, expected := h.Quantile(1), h.Bound(20); actual != expected {
+		t.Errorf("Actual: %s; Expected: %s", actual, expected)
+	}
+}
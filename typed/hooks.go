@@ -0,0 +1,131 @@
+package typed
+
+import (
+	"fmt"
+	"time"
+)
+
+// LifecycleHooks holds optional functions called by a pool as items move through it, for instance
+// to log, trace, or account for their use. Each hook is called outside the pool's internal locks,
+// so it may take its time, although a slow hook slows the caller that triggered it. A hook that
+// panics is handled like any other callback that panics. Hooks left nil are not called.
+type LifecycleHooks[T any] struct {
+	// OnCreate is called with each item the factory creates.
+	OnCreate func(item T)
+
+	// OnGet is called with each item handed to a caller, along with how long the caller waited
+	// for it, including any time spent creating it.
+	OnGet func(item T, wait time.Duration)
+
+	// OnPut is called with each item released back to the pool, along with how long it was
+	// checked out, which is zero for an item that was not checked out of the pool. It is called
+	// after the item has either been added back to the pool, or discarded.
+	OnPut func(item T, held time.Duration)
+
+	// OnDiscard is called with each item the pool gives up, along with the reason why, before the
+	// item is passed to the optional close function.
+	OnDiscard func(item T, reason DiscardReason)
+
+	// OnFactoryError is called with the error from each failed call to the factory, including
+	// calls that are retried.
+	OnFactoryError func(err error)
+
+	// OnClose is called one time, when the pool is closed, with the error returned by Close.
+	OnClose func(err error)
+}
+
+// Hooks specifies the optional functions called by the pool as items move through it.
+func Hooks[T any](hooks LifecycleHooks[T]) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.hooks = hooks
+		return nil
+	}
+}
+
+// DiscardReason describes why a pool discarded an item.
+type DiscardReason int
+
+const (
+	DiscardCaller   DiscardReason = iota // caller passed the item to Discard
+	DiscardClosed                        // pool was closed, or could not be filled
+	DiscardExpired                       // item exceeded its MaxIdleTime or MaxLifetime
+	DiscardInvalid                       // item failed ValidateOnGet or ValidateOnPut
+	DiscardRejected                      // ResetOrDiscard rejected the item
+	DiscardPanicked                      // callback invoked with the item panicked
+	DiscardFull                          // pool had no room for the item, such as a temporary item
+)
+
+func (r DiscardReason) String() string {
+	switch r {
+	case DiscardCaller:
+		return "caller"
+	case DiscardClosed:
+		return "closed"
+	case DiscardExpired:
+		return "expired"
+	case DiscardInvalid:
+		return "invalid"
+	case DiscardRejected:
+		return "rejected"
+	case DiscardPanicked:
+		return "panicked"
+	case DiscardFull:
+		return "full"
+	default:
+		return fmt.Sprintf("DiscardReason(%d)", int(r))
+	}
+}
+
+// notify invokes fn, which calls the named hook, recovering from any panic.
+func (b *base[T]) notify(hook string, fn func()) {
+	_ = b.guard(hook, func() error {
+		fn()
+		return nil
+	})
+}
+
+// created invokes the optional OnCreate hook.
+func (b *base[T]) created(item T) {
+	if h := b.pc.hooks.OnCreate; h != nil {
+		b.notify("OnCreate", func() { h(item) })
+	}
+}
+
+// got invokes the optional OnGet hook for an item requested at start.
+func (b *base[T]) got(item T, start time.Time) {
+	if h := b.pc.hooks.OnGet; h != nil {
+		b.notify("OnGet", func() { h(item, time.Since(start)) })
+	}
+}
+
+// returned invokes the optional OnPut hook for an item released back to the pool, whose entry is e.
+func (b *base[T]) returned(item T, e entry[T]) {
+	if h := b.pc.hooks.OnPut; h != nil {
+		var held time.Duration
+		if !e.borrowed.IsZero() {
+			held = time.Since(e.borrowed)
+		}
+		b.notify("OnPut", func() { h(item, held) })
+	}
+}
+
+// discarded invokes the optional OnDiscard hook.
+func (b *base[T]) discarded(item T, reason DiscardReason) {
+	if h := b.pc.hooks.OnDiscard; h != nil {
+		b.notify("OnDiscard", func() { h(item, reason) })
+	}
+}
+
+// factoryFailed invokes the optional OnFactoryError hook.
+func (b *base[T]) factoryFailed(err error) {
+	if h := b.pc.hooks.OnFactoryError; h != nil {
+		b.notify("OnFactoryError", func() { h(err) })
+	}
+}
+
+// poolClosed invokes the optional OnClose hook.
+func (b *base[T]) poolClosed(err error) {
+	if h := b.pc.hooks.OnClose; h != nil {
+		b.notify("OnClose", func() { h(err) })
+	}
+}
@@ -0,0 +1,68 @@
+package typed
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrReleased is returned when releasing or discarding a Lease that has already been released or
+// discarded.
+var ErrReleased = errors.New("lease already released")
+
+// Lease holds an item checked out of a pool by Acquire or AcquireContext. Unlike an item from Get,
+// which may mistakenly be released back to the pool more than once, each Lease returns its item to
+// the pool at most one time: the first call to either Release or Discard gives up the item, and
+// every later call is rejected with ErrReleased, and counted in the pool's Stats.
+type Lease[T any] struct {
+	pool     lessor[T]
+	counters *counters
+	item     T
+	released uint32 // set to 1 by the first Release or Discard
+}
+
+// lessor is implemented by the pools that hand out leases.
+type lessor[T any] interface {
+	Put(T)
+	Discard(T) error
+	get(ctx context.Context, wait bool) (T, error)
+}
+
+// acquire returns a Lease for an item checked out of pool. When wait is false, acquire returns
+// ErrExhausted rather than block waiting for an item.
+func acquire[T any](ctx context.Context, pool lessor[T], counters *counters, wait bool) (*Lease[T], error) {
+	item, err := pool.get(ctx, wait)
+	if err != nil {
+		return nil, err
+	}
+	return &Lease[T]{pool: pool, counters: counters, item: item}, nil
+}
+
+// Item returns the leased item. The item must not be used after the lease is released or
+// discarded.
+func (l *Lease[T]) Item() T {
+	return l.item
+}
+
+// Release returns the leased item to the pool, as if by Put. It returns ErrReleased without
+// returning the item when the lease has already been released or discarded.
+func (l *Lease[T]) Release() error {
+	if !atomic.CompareAndSwapUint32(&l.released, 0, 1) {
+		atomic.AddUint64(&l.counters.doubleReleases, 1)
+		return ErrReleased
+	}
+	l.pool.Put(l.item)
+	return nil
+}
+
+// Discard passes the leased item to the optional close function rather than returning it to the
+// pool, for instance when the item is broken, and returns the error from closing it. The pool
+// creates a replacement item as needed. Discard returns ErrReleased without closing the item when
+// the lease has already been released or discarded.
+func (l *Lease[T]) Discard() error {
+	if !atomic.CompareAndSwapUint32(&l.released, 0, 1) {
+		atomic.AddUint64(&l.counters.doubleReleases, 1)
+		return ErrReleased
+	}
+	return l.pool.Discard(l.item)
+}
@@ -0,0 +1,169 @@
+package typed
+
+import "reflect"
+
+// ledger records the items checked out of a pool, along with their bookkeeping, so they can be
+// accounted for when returned to the pool or when the pool is shut down. Because the same value may
+// be checked out more than once, for instance when a factory returns nil items, the ledger records
+// every entry for each item. Items that cannot be used as map keys, such as byte slices, or structs
+// holding byte slices in interface fields, cannot be told apart when they are returned, so each
+// returned untracked item is paired with the entry of the untracked item checked out the longest.
+// The number of untracked items, and whether each entry is for a temporary item, thus remain
+// correct, although the other bookkeeping of an entry may describe a different untracked item. The
+// ledger also counts the tracked items idle in the pool, along with those closed by Close or
+// Shutdown once the pool is closed, so an item released back to the pool while already idle or
+// closed can be recognized. The zero value is an empty ledger ready for use.
+type ledger[T any] struct {
+	items  map[any]record[T]
+	loose  []entry[T]  // entries of checked out untracked items, checked out the longest first
+	count  int         // number of items checked out, including untracked items
+	parked map[any]int // number of times each tracked item is idle in the pool, or closed by it
+}
+
+// record holds the entries for one checked out item. Only items checked out more than once use the
+// more slice.
+type record[T any] struct {
+	entry entry[T]
+	more  []entry[T]
+}
+
+// hashable returns true when item may be used as a map key without panicking. A value of a
+// comparable struct or array type may still panic when hashed, when an interface within it holds a
+// value that cannot be hashed, such as a slice, so such values are hashed once here to find out.
+func hashable(item any) (ok bool) {
+	t := reflect.TypeOf(item)
+	if t == nil {
+		return true
+	}
+	if !t.Comparable() {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Array:
+		defer func() {
+			if recover() != nil {
+				ok = false
+			}
+		}()
+		_ = hashProbe[item]
+	}
+	return true
+}
+
+// hashProbe is an empty map used by hashable to hash values.
+var hashProbe = map[any]struct{}{}
+
+// add records the item in e as checked out.
+func (l *ledger[T]) add(e entry[T]) {
+	l.count++
+	key := any(e.item)
+	if !hashable(key) {
+		l.loose = append(l.loose, e)
+		return
+	}
+	if l.items == nil {
+		l.items = make(map[any]record[T])
+	}
+	if r, ok := l.items[key]; ok {
+		r.more = append(r.more, e)
+		l.items[key] = r
+		return
+	}
+	l.items[key] = record[T]{entry: e}
+}
+
+// remove records item as returned, and returns its entry and true when item was checked out. An
+// untracked item is presumed to have been checked out while any untracked items remain outstanding,
+// and is given the entry of the untracked item checked out the longest.
+func (l *ledger[T]) remove(item T) (entry[T], bool) {
+	key := any(item)
+	if !hashable(key) {
+		if len(l.loose) == 0 {
+			return entry[T]{}, false
+		}
+		e := l.loose[0]
+		l.loose[0] = entry[T]{} // do not retain reference to returned item
+		l.loose = l.loose[1:]
+		l.count--
+		e.item = item
+		return e, true
+	}
+	r, ok := l.items[key]
+	if !ok {
+		return entry[T]{}, false
+	}
+	l.count--
+	if n := len(r.more); n > 0 {
+		e := r.more[n-1]
+		r.more = r.more[:n-1]
+		l.items[key] = r
+		return e, true
+	}
+	delete(l.items, key)
+	return r.entry, true
+}
+
+// park records item as idle in the pool.
+func (l *ledger[T]) park(item T) {
+	key := any(item)
+	if !hashable(key) {
+		return
+	}
+	if l.parked == nil {
+		l.parked = make(map[any]int)
+	}
+	l.parked[key]++
+}
+
+// unpark records item as no longer idle in the pool.
+func (l *ledger[T]) unpark(item T) {
+	key := any(item)
+	if !hashable(key) {
+		return
+	}
+	if n := l.parked[key]; n > 1 {
+		l.parked[key] = n - 1
+	} else {
+		delete(l.parked, key)
+	}
+}
+
+// idle returns true when item is a tracked item idle in the pool.
+func (l *ledger[T]) idle(item T) bool {
+	key := any(item)
+	return hashable(key) && l.parked[key] > 0
+}
+
+// each invokes fn with the entry of every tracked item checked out.
+func (l *ledger[T]) each(fn func(entry[T])) {
+	for _, r := range l.items {
+		fn(r.entry)
+		for _, e := range r.more {
+			fn(e)
+		}
+	}
+}
+
+// len returns the number of items checked out.
+func (l *ledger[T]) len() int {
+	return l.count
+}
+
+// drain empties the ledger, returning each tracked item once for every time it is checked out, and
+// records those items as idle, because the caller closes them. Untracked items are forgotten.
+func (l *ledger[T]) drain() []T {
+	var items []T
+	for _, r := range l.items {
+		items = append(items, r.entry.item)
+		for _, e := range r.more {
+			items = append(items, e.item)
+		}
+	}
+	l.items = nil
+	l.loose = nil
+	l.count = 0
+	for _, item := range items {
+		l.park(item)
+	}
+	return items
+}
@@ -0,0 +1,73 @@
+package typed
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Ordering determines which idle item a pool hands out next.
+type Ordering int
+
+const (
+	// FIFO hands out the item idle the longest, spreading use evenly across every item. It is the
+	// default.
+	FIFO Ordering = iota
+
+	// LIFO hands out the item most recently released back to the pool, reusing the items most
+	// likely to be in CPU cache, and leaving the others idle long enough for MaxIdleTime to close
+	// them when demand drops.
+	LIFO
+
+	// Random hands out an idle item chosen at random.
+	Random
+)
+
+func (o Ordering) String() string {
+	switch o {
+	case FIFO:
+		return "FIFO"
+	case LIFO:
+		return "LIFO"
+	case Random:
+		return "Random"
+	default:
+		return fmt.Sprintf("Ordering(%d)", int(o))
+	}
+}
+
+// Order specifies which idle item the pool hands out next. By default, pools are FIFO.
+func Order[T any](order Ordering) Configurator[T] {
+	return func(pc *config[T]) error {
+		switch order {
+		case FIFO, LIFO, Random:
+		default:
+			return fmt.Errorf("pool order must be FIFO, LIFO, or Random: %s", order)
+		}
+		pc.order = order
+		return nil
+	}
+}
+
+// takeIdle removes and returns an entry from idle, which holds entries in the order they were
+// released back to the pool, choosing the entry according to order.
+func takeIdle[T any](idle *[]entry[T], order Ordering) entry[T] {
+	s := *idle
+	var i int
+	switch order {
+	case LIFO:
+		i = len(s) - 1
+	case Random:
+		i = rand.Intn(len(s))
+	}
+	e := s[i]
+	if i == 0 {
+		s[0] = entry[T]{} // do not retain reference to item while checked out
+		*idle = s[1:]
+		return e
+	}
+	n := len(s) - 1
+	s[i] = s[n]
+	s[n] = entry[T]{} // do not retain reference to item while checked out
+	*idle = s[:n]
+	return e
+}
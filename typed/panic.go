@@ -0,0 +1,42 @@
+package typed
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// PanicError is returned in place of the error from a callback that panics, and is reported to the
+// optional OnPanic hook. The pool remains usable, and discards the item the callback was invoked
+// with, if any.
+type PanicError struct {
+	Callback string // name of the configurator that specified the callback, such as "Reset"
+	Value    any    // value passed to panic
+	Stack    []byte // stack of the goroutine that panicked, from debug.Stack
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pool %s callback panicked: %v", e.Callback, e.Value)
+}
+
+// Unwrap returns the value passed to panic when it is an error, and otherwise nil.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// guard returns the result of fn, which invokes the named callback, or a *PanicError when fn panics,
+// after reporting the panic to the optional OnPanic hook.
+func (b *base[T]) guard(callback string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			pe := &PanicError{Callback: callback, Value: v, Stack: debug.Stack()}
+			atomic.AddUint64(&b.counters.panics, 1)
+			if b.pc.onPanic != nil {
+				b.pc.onPanic(pe)
+			}
+			err = pe
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,517 @@
+// Package typed offers type-safe free-lists, or pools of resources, parameterized by the type of
+// resource being pooled.
+//
+// The gopool package provides the original interface{} API as a thin wrapper around this package.
+//
+// A pool recognizes each item released back to it by comparing the item with the items it checked
+// out. Items that cannot be compared, such as byte slices, or structs holding byte slices in
+// interface fields, cannot be told apart, so the pool pairs each such item released back to it
+// with the earliest outstanding checkout of such an item. The pool still counts them correctly,
+// including temporary items created by Overflow, and still expires them according to MaxLifetime,
+// although when they are released in a different order than they were checked out, an item may be
+// closed sooner or later than its own lifetime calls for. Outstanding does not list them, and
+// Shutdown does not close those that are never released.
+package typed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSize is the default number of items that will be maintained in the pool.
+const DefaultSize = 10
+
+// ErrClosed is returned when attempting to get an item from a Pool that has been closed, including
+// to callers that were blocked waiting for an item when the Pool was closed.
+var ErrClosed = errors.New("pool closed")
+
+// ErrExhausted is returned when attempting to get an item without blocking from a Pool that has no
+// idle items and cannot create more.
+var ErrExhausted = errors.New("pool exhausted")
+
+// ErrInvalidSize is wrapped by the error returned when creating a Pool with an invalid size, maximum
+// size, minimum number of idle items, or overflow.
+var ErrInvalidSize = errors.New("invalid pool size")
+
+// ErrNoFactory is returned when creating a Pool without specifying a factory method.
+var ErrNoFactory = errors.New("cannot create pool without specifying a factory method")
+
+// ErrNotCheckedOut is returned when discarding an item that is not checked out of a Pool, for
+// instance because it has already been released or discarded.
+var ErrNotCheckedOut = errors.New("item not checked out of pool")
+
+// ErrTimeout is wrapped by the error returned when a context's deadline expires while waiting for an
+// item from a Pool. The error also wraps context.DeadlineExceeded.
+var ErrTimeout = errors.New("timed out waiting for pool item")
+
+// CloseError is returned when the close function returns an error for one or more items while
+// closing a Pool. Errs[i] is the error returned when closing Items[i].
+type CloseError[T any] struct {
+	Items []T
+	Errs  []error
+}
+
+func (e *CloseError[T]) Error() string {
+	messages := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Is reports whether any of the errors returned by the close function matches target.
+func (e *CloseError[T]) Is(target error) bool {
+	for _, err := range e.Errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors returned by the close function that matches target, and if one
+// is found, sets target to that error value and returns true.
+func (e *CloseError[T]) As(target any) bool {
+	for _, err := range e.Errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// FillError is returned when the factory fails while filling a new Pool. The items created before the
+// failure have already been passed to the optional close function.
+type FillError struct {
+	Index    int   // index of the item the factory failed to create
+	Err      error // error returned by the factory
+	CloseErr error // *CloseError from closing the items created before the failure, or nil
+}
+
+func (e *FillError) Error() string {
+	if e.CloseErr != nil {
+		return fmt.Sprintf("cannot fill pool: factory failed creating item %d: %s; cannot close created items: %s", e.Index, e.Err, e.CloseErr)
+	}
+	return fmt.Sprintf("cannot fill pool: factory failed creating item %d: %s", e.Index, e.Err)
+}
+
+func (e *FillError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the error from closing the items created before the failure matches target.
+// The error returned by the factory is matched through Unwrap.
+func (e *FillError) Is(target error) bool {
+	return e.CloseErr != nil && errors.Is(e.CloseErr, target)
+}
+
+// As finds whether the error from closing the items created before the failure matches target, and
+// if so, sets target to that error value and returns true.
+func (e *FillError) As(target any) bool {
+	return e.CloseErr != nil && errors.As(e.CloseErr, target)
+}
+
+// timeoutError is returned when a context's deadline expires while waiting for an item. It wraps
+// the context's error, and matches ErrTimeout.
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string {
+	return ErrTimeout.Error() + ": " + e.err.Error()
+}
+
+func (e *timeoutError) Unwrap() error {
+	return e.err
+}
+
+func (e *timeoutError) Is(target error) bool {
+	return target == ErrTimeout
+}
+
+// waitError returns the error for a caller that stopped waiting for an item because ctx is done.
+// When the context's deadline expired, the error wraps both ErrTimeout and the context's error.
+func waitError(ctx context.Context) error {
+	err := ctx.Err()
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &timeoutError{err: err}
+	}
+	return err
+}
+
+// broadcastWhenDone wakes all callers waiting on cond when ctx is done. A goroutine blocked in Wait
+// cannot select on the context, so this allows a waiter to notice the context is done and bail out.
+// The returned function must be called to release resources once the caller no longer waits.
+func broadcastWhenDone(ctx context.Context, cond *sync.Cond) func() {
+	if ctx.Done() == nil {
+		return func() {} // context can never be done
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cond.L.Lock()
+			cond.Broadcast()
+			cond.L.Unlock()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// OutstandingError is returned by Shutdown when its context is done before every checked out item
+// has been returned to the pool.
+type OutstandingError[T any] struct {
+	Err   error // reason Shutdown stopped waiting, namely the context's error
+	Items []T   // checked out items that were not returned, and were closed by Shutdown
+}
+
+func (e *OutstandingError[T]) Error() string {
+	return fmt.Sprintf("%d items not returned to pool: %s", len(e.Items), e.Err)
+}
+
+func (e *OutstandingError[T]) Unwrap() error {
+	return e.Err
+}
+
+// Pool is the interface implemented by an object that acts as a free-list resource pool of items of
+// type T.
+type Pool[T any] interface {
+	Acquire() (*Lease[T], error)
+	AcquireContext(context.Context) (*Lease[T], error)
+	Close() error
+	Discard(T) error
+	Get() T
+	GetContext(context.Context) (T, error)
+	Outstanding(threshold time.Duration) []Borrow[T]
+	Put(T)
+	Shutdown(context.Context) error
+	Stats() Stats
+	TryAcquire() (*Lease[T], error)
+	TryGet() (T, bool)
+	TryPut(T) bool
+}
+
+type config[T any] struct {
+	close   func(T) error
+	factory func(context.Context) (T, error)
+	retry   RetryPolicy
+	reset   func(T)
+
+	resetOrDiscard func(T) error
+
+	validateOnGet func(T) error
+	validateOnPut func(T) error
+
+	trackBorrowers bool
+	order          Ordering
+
+	onPanic func(*PanicError)
+	hooks   LifecycleHooks[T]
+
+	size    int  // maximum number of items once resolved by newConfig
+	maxSize int  // zero when not specified
+	minIdle int  // number of idle items to keep warm
+	lazy    bool // true when either MinIdle or MaxSize specified
+
+	maxExtra int // number of temporary items that may be created beyond size
+
+	fillConcurrency int           // maximum number of factory calls at once while filling the pool
+	fillTimeout     time.Duration // zero when filling the pool does not time out
+
+	context context.Context // parent of the context passed to the factory for no particular caller
+
+	maxIdleTime  time.Duration
+	maxLifetime  time.Duration
+	expiryJitter time.Duration
+}
+
+// newConfig returns a pool configuration after applying setters to the default configuration,
+// resolving the maximum number of items, and the number of idle items to keep warm. Without either
+// MinIdle or MaxSize, a pool is eagerly filled with Size items.
+func newConfig[T any](setters []Configurator[T]) (*config[T], error) {
+	pc := &config[T]{
+		size:            DefaultSize,
+		fillConcurrency: 1,
+		context:         context.Background(),
+	}
+	for _, setter := range setters {
+		if err := setter(pc); err != nil {
+			return nil, err
+		}
+	}
+	if pc.factory == nil {
+		return nil, ErrNoFactory
+	}
+	if pc.maxSize > 0 {
+		pc.size = pc.maxSize
+	}
+	if !pc.lazy {
+		pc.minIdle = pc.size
+	}
+	if pc.minIdle > pc.size {
+		return nil, fmt.Errorf("%w: minimum idle items must not be greater than maximum size: %d > %d", ErrInvalidSize, pc.minIdle, pc.size)
+	}
+	if pc.expiryJitter > 0 {
+		for _, d := range []time.Duration{pc.maxIdleTime, pc.maxLifetime} {
+			if d > 0 && pc.expiryJitter >= d {
+				return nil, fmt.Errorf("pool expiry jitter must be less than maximum idle time and maximum lifetime: %s >= %s", pc.expiryJitter, d)
+			}
+		}
+	}
+	return pc, nil
+}
+
+// Configurator is a function that modifies a pool configuration structure.
+type Configurator[T any] func(*config[T]) error
+
+// Close specifies the optional function to be called once for each resource when the Pool is
+// closed.
+func Close[T any](close func(T) error) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.close = close
+		return nil
+	}
+}
+
+// ConstructionContext specifies the parent of the context passed to a FactoryContext factory when
+// creating items for no particular caller, namely while filling the pool during initialization,
+// and while creating idle items in the background. Canceling ctx abandons creating those items. By
+// default, the parent context is context.Background.
+func ConstructionContext[T any](ctx context.Context) Configurator[T] {
+	return func(pc *config[T]) error {
+		if ctx == nil {
+			return errors.New("pool construction context must not be nil")
+		}
+		pc.context = ctx
+		return nil
+	}
+}
+
+// Factory specifies the function used to make new elements for the pool.  The factory function is
+// called to fill the pool N times during initialization, for a pool size of N. When either MinIdle
+// or MaxSize is specified, the factory function is instead called to create items on demand.
+func Factory[T any](factory func() (T, error)) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.factory = func(context.Context) (T, error) { return factory() }
+		return nil
+	}
+}
+
+// FactoryContext specifies the function used to make new elements for the pool, like Factory, but
+// for a factory that accepts a context, so that creating an item may be abandoned. When GetContext
+// creates an item, the factory is passed the caller's context. Otherwise the factory is passed a
+// context derived from the ConstructionContext, which is canceled when the pool is closed, or
+// while filling the pool during initialization, when the FillTimeout elapses.
+func FactoryContext[T any](factory func(context.Context) (T, error)) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.factory = factory
+		return nil
+	}
+}
+
+// ExpiryJitter specifies the maximum random duration subtracted from the maximum idle time and
+// maximum lifetime of each item, so that items created or returned to the pool at the same time do
+// not all expire at the same time.
+func ExpiryJitter[T any](jitter time.Duration) Configurator[T] {
+	return func(pc *config[T]) error {
+		if jitter < 0 {
+			return fmt.Errorf("pool expiry jitter must not be negative: %s", jitter)
+		}
+		pc.expiryJitter = jitter
+		return nil
+	}
+}
+
+// FillConcurrency specifies the maximum number of concurrent factory calls made to create the
+// initial items of the pool. By default, the initial items are created one after another.
+func FillConcurrency[T any](n int) Configurator[T] {
+	return func(pc *config[T]) error {
+		if n <= 0 {
+			return fmt.Errorf("pool fill concurrency must be greater than 0: %d", n)
+		}
+		pc.fillConcurrency = n
+		return nil
+	}
+}
+
+// FillTimeout specifies the maximum duration allowed to create the initial items of the pool. When
+// it elapses, no more factory calls are made, the items already created are passed to the optional
+// close function, and creating the pool fails with a *FillError that wraps ErrTimeout. Items
+// returned by factory calls outstanding at that time are passed to the close function as those
+// calls return.
+func FillTimeout[T any](d time.Duration) Configurator[T] {
+	return func(pc *config[T]) error {
+		if d <= 0 {
+			return fmt.Errorf("pool fill timeout must be greater than 0: %s", d)
+		}
+		pc.fillTimeout = d
+		return nil
+	}
+}
+
+// MaxIdleTime specifies the maximum duration an item may remain idle in the pool. A background
+// reaper passes each item that remains idle longer than this to the optional close function, and
+// the pool creates replacement items as needed.
+func MaxIdleTime[T any](d time.Duration) Configurator[T] {
+	return func(pc *config[T]) error {
+		if d <= 0 {
+			return fmt.Errorf("pool maximum idle time must be greater than 0: %s", d)
+		}
+		pc.maxIdleTime = d
+		return nil
+	}
+}
+
+// MaxLifetime specifies the maximum duration an item may be used after being created. A background
+// reaper passes each idle item older than this to the optional close function, as does Put for each
+// released item older than this, and the pool creates replacement items as needed. See the package
+// documentation for how this applies to items that cannot be compared.
+func MaxLifetime[T any](d time.Duration) Configurator[T] {
+	return func(pc *config[T]) error {
+		if d <= 0 {
+			return fmt.Errorf("pool maximum lifetime must be greater than 0: %s", d)
+		}
+		pc.maxLifetime = d
+		return nil
+	}
+}
+
+// MaxSize specifies the maximum number of items the pool may hold, and causes the pool to create
+// items on demand rather than filling the pool during initialization. When all items are checked
+// out and the pool holds fewer than the maximum number of items, Get creates a new item rather than
+// waiting for an item to be returned to the pool. MaxSize takes precedence over Size.
+func MaxSize[T any](size int) Configurator[T] {
+	return func(pc *config[T]) error {
+		if size <= 0 {
+			return fmt.Errorf("%w: maximum size must be greater than 0: %d", ErrInvalidSize, size)
+		}
+		pc.maxSize = size
+		pc.lazy = true
+		return nil
+	}
+}
+
+// MinIdle specifies the number of idle items the pool keeps warm, and causes the pool to create
+// items on demand rather than filling the pool during initialization. The pool creates this many
+// items during initialization, and whenever the number of idle items drops below it, creates more
+// items in the background, so long as the pool does not exceed its maximum size.
+func MinIdle[T any](count int) Configurator[T] {
+	return func(pc *config[T]) error {
+		if count < 0 {
+			return fmt.Errorf("%w: minimum idle items must not be negative: %d", ErrInvalidSize, count)
+		}
+		pc.minIdle = count
+		pc.lazy = true
+		return nil
+	}
+}
+
+// OnPanic specifies the optional function to be called when a callback, such as the factory, reset,
+// validation, or close function, panics. The panic is recovered and converted to a *PanicError,
+// which is passed to the hook, and returned in place of the error from the callback, if any. The
+// item the callback was invoked with, if any, is discarded, and the pool remains usable.
+func OnPanic[T any](hook func(*PanicError)) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.onPanic = hook
+		return nil
+	}
+}
+
+// Overflow specifies the number of temporary items the pool may create beyond its capacity. When
+// every item is checked out and the pool already holds its maximum number of items, Get creates a
+// temporary item from the factory rather than waiting for an item to be returned to the pool, so
+// long as fewer than maxExtra temporary items are checked out. When a temporary item is released
+// back to a full pool, it is passed to the optional reset and close functions and dropped.
+func Overflow[T any](maxExtra int) Configurator[T] {
+	return func(pc *config[T]) error {
+		if maxExtra < 0 {
+			return fmt.Errorf("%w: overflow must not be negative: %d", ErrInvalidSize, maxExtra)
+		}
+		pc.maxExtra = maxExtra
+		return nil
+	}
+}
+
+// Reset specifies the optional function to be called on resources when released back to the pool.
+// If a reset function is not specified, then resources are returned to the pool without any reset
+// step.  For instance, if maintaining a Pool of buffers, a library may choose to have the reset
+// function invoke the buffer's Reset method to free resources prior to returning the buffer to the
+// Pool.
+func Reset[T any](reset func(T)) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.reset = reset
+		return nil
+	}
+}
+
+// ResetOrDiscard specifies the optional function to be called on resources when released back to
+// the pool, after any reset function, which may reject a resource that ought not be reused by
+// returning an error. For instance, a pool of buffers may reject a buffer that grew too large while
+// in use, so it does not hold on to that memory. A rejected resource is passed to the optional close
+// function rather than being added back to the pool, and the pool creates replacement resources
+// from the factory as needed.
+func ResetOrDiscard[T any](reset func(T) error) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.resetOrDiscard = reset
+		return nil
+	}
+}
+
+// Size specifies the number of items to maintain in the pool.
+func Size[T any](size int) Configurator[T] {
+	return func(pc *config[T]) error {
+		if size <= 0 {
+			return fmt.Errorf("%w: size must be greater than 0: %d", ErrInvalidSize, size)
+		}
+		pc.size = size
+		return nil
+	}
+}
+
+// Stack specifies whether the pool hands out the idle item most recently released back to the
+// pool, like a stack, rather than the item idle the longest, like a queue, which is the default.
+// Stack(true) is equivalent to Order(LIFO), and Stack(false) to Order(FIFO).
+func Stack[T any](stack bool) Configurator[T] {
+	if stack {
+		return Order[T](LIFO)
+	}
+	return Order[T](FIFO)
+}
+
+// TrackBorrowers specifies whether the pool records the time and call stack of each Get, so that
+// items held longer than expected, for instance because a caller neglected to release them back to
+// the pool, can be listed by Outstanding along with where they were checked out. Tracking borrowers
+// adds the cost of recording a call stack to each Get. Items that cannot be compared, such as byte
+// slices, are not tracked.
+func TrackBorrowers[T any](track bool) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.trackBorrowers = track
+		return nil
+	}
+}
+
+// ValidateOnGet specifies the optional function to be called on an idle resource before handing it
+// to a caller. When validation fails, the resource is passed to the optional close function, the
+// caller receives another resource, and the pool creates replacement resources as needed.
+func ValidateOnGet[T any](validate func(T) error) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.validateOnGet = validate
+		return nil
+	}
+}
+
+// ValidateOnPut specifies the optional function to be called on a resource released back to the
+// pool, after any reset function. When validation fails, the resource is passed to the optional
+// close function rather than being added back to the pool, and the pool creates replacement
+// resources as needed.
+func ValidateOnPut[T any](validate func(T) error) Configurator[T] {
+	return func(pc *config[T]) error {
+		pc.validateOnPut = validate
+		return nil
+	}
+}
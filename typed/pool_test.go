@@ -0,0 +1,2512 @@
+package typed_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karrick/gopool/typed"
+)
+
+const defaultBufSize = 1024
+const defaultMaxKeep = 1024 * 128
+
+const lowConcurrency = 16
+const medConcurrency = 128
+const highConcurrency = 1024
+
+const lowCap = 100
+const medCap = 1000
+const largeCap = 10000
+
+////////////////////////////////////////
+
+func makeBuffer() (*bytes.Buffer, error) {
+	return bytes.NewBuffer(make([]byte, defaultBufSize)), nil
+}
+
+func resetBuffer(bb *bytes.Buffer) {
+	bb.Reset()
+}
+
+func closeBuffer(bb *bytes.Buffer) error {
+	bb.Reset()
+	return nil
+}
+
+// contextKey is the key of values that tests store in a context.
+type contextKey struct{}
+
+////////////////////////////////////////
+
+func testC(bp typed.Pool[*bytes.Buffer], concurrency, loops int) {
+	const byteCount = defaultBufSize / 2
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for c := 0; c < concurrency; c++ {
+		go func() {
+			for j := 0; j < loops; j++ {
+				bb := bp.Get()
+				max := byteCount
+				if j%8 == 0 {
+					max = 2 * defaultMaxKeep
+				}
+				for k := 0; k < max; k++ {
+					bb.WriteByte(byte(k % 256))
+				}
+				bp.Put(bb)
+			}
+			wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func test(t *testing.T, bp typed.Pool[*bytes.Buffer]) {
+	const concurrency = 128
+	const loops = 128
+	testC(bp, concurrency, loops)
+}
+
+func bench(b *testing.B, bp typed.Pool[*bytes.Buffer], concurrency int) {
+	b.ResetTimer() // do not include initialization time in benchmarks
+	testC(bp, concurrency, b.N)
+}
+
+////////////////////////////////////////
+
+// implementation names a pool implementation.
+type implementation struct {
+	name string
+}
+
+// implementations lists each pool implementation, so tests may verify that every implementation
+// behaves the same way.
+var implementations = []implementation{
+	{"ChanPool"},
+	{"ArrayPool"},
+	{"SemaphorePool"},
+}
+
+// newPool creates a new pool of items of any type using the implementation impl.
+func newPool[T any](impl implementation, setters ...typed.Configurator[T]) (typed.Pool[T], error) {
+	switch impl.name {
+	case "ArrayPool":
+		return typed.NewArray(setters...)
+	case "SemaphorePool":
+		return typed.NewSemaphore(setters...)
+	default:
+		return typed.NewChan(setters...)
+	}
+}
+
+// putWithin fails the test when Put does not return within a second.
+func putWithin(t *testing.T, pool typed.Pool[int], item int) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		pool.Put(item)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("Put(%d) blocked", item)
+	}
+}
+
+func TestPoolsPutOnFullPoolDropsItem(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var resets, closes int32
+			pool, err := newPool(impl, typed.Size[int](2),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}),
+				typed.Reset(func(int) {
+					atomic.AddInt32(&resets, 1)
+				}),
+				typed.Close(func(int) error {
+					atomic.AddInt32(&closes, 1)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					putWithin(t, pool, 100+i)
+				}(i)
+			}
+			wg.Wait()
+
+			if actual, expected := atomic.LoadInt32(&resets), int32(10); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := atomic.LoadInt32(&closes), int32(10); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Idle, 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsPutForeignItemsWhileRoom(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](3),
+				typed.Factory(func() (int, error) {
+					atomic.AddInt32(&factoryInvoked, 1)
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			var kept []bool
+			for i := 1; i <= 5; i++ {
+				kept = append(kept, pool.TryPut(i))
+			}
+			if actual, expected := kept, []bool{true, true, true, false, false}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// pool holds its maximum number of items, so Get does not create more
+			var items []int
+			for i := 0; i < 3; i++ {
+				items = append(items, pool.Get())
+			}
+			if actual, expected := items, []int{1, 2, 3}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if _, ok := pool.TryGet(); ok {
+				t.Errorf("Actual: %#v; Expected: %#v", ok, false)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(0); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsBehaveTheSame(t *testing.T) {
+	var traces [][]string
+	for _, impl := range implementations {
+		var trace []string
+		var factoryInvoked int32
+		pool, err := newPool(impl, typed.Size[int](2), typed.Overflow[int](1),
+			typed.Factory(func() (int, error) {
+				return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+			}),
+			typed.Close(func(item int) error {
+				trace = append(trace, fmt.Sprintf("close %d", item))
+				return nil
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		get := func() int {
+			item, ok := pool.TryGet()
+			trace = append(trace, fmt.Sprintf("get %d %t", item, ok))
+			return item
+		}
+		put := func(item int) {
+			trace = append(trace, fmt.Sprintf("put %d %t", item, pool.TryPut(item)))
+		}
+
+		a, b, c := get(), get(), get()
+		get()
+		put(99)
+		put(c)
+		put(a)
+		put(b)
+		put(98)
+		get()
+		st := pool.Stats()
+		trace = append(trace, fmt.Sprintf("stats %d %d %d %d %d", st.Idle, st.InUse, st.Extra, st.Overflows, st.Puts))
+		if err := pool.Close(); err != nil {
+			t.Fatal(err)
+		}
+		put(a)
+
+		// A caller waiting for an item is woken to create a temporary item when another temporary
+		// item is released and dropped.
+		pool, err = newPool(impl, typed.Size[int](1), typed.Overflow[int](1),
+			typed.Factory(func() (int, error) {
+				return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, b = get(), get()
+		waited := make(chan string)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			item, err := pool.GetContext(ctx)
+			waited <- fmt.Sprintf("wait %d %v", item, err)
+		}()
+		for pool.Stats().Waiters == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		put(b)
+		trace = append(trace, <-waited)
+		if err := pool.Close(); err != nil {
+			t.Fatal(err)
+		}
+		traces = append(traces, trace)
+	}
+	for i := 1; i < len(traces); i++ {
+		if actual, expected := traces[i], traces[0]; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: Actual: %#v; Expected: %#v", implementations[i].name, actual, expected)
+		}
+	}
+}
+
+func TestPoolsRejectReleasingIdleItem(t *testing.T) {
+	for _, impl := range implementations {
+		for _, tc := range []struct {
+			name string
+			size typed.Configurator[*int]
+		}{
+			{"Size", typed.Size[*int](2)},
+			{"MaxSize", typed.MaxSize[*int](2)},
+		} {
+			t.Run(impl.name+"/"+tc.name, func(t *testing.T) {
+				var closeInvoked int32
+				pool, err := newPool(impl, tc.size,
+					typed.Factory(func() (*int, error) {
+						return new(int), nil
+					}),
+					typed.Close(func(*int) error {
+						atomic.AddInt32(&closeInvoked, 1)
+						return nil
+					}))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer pool.Close()
+
+				c := pool.Get()
+				if actual, expected := pool.TryPut(c), true; actual != expected {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+				if actual, expected := pool.TryPut(c), false; actual != expected {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+				if actual, expected := atomic.LoadInt32(&closeInvoked), int32(0); actual != expected {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+				if actual, expected := pool.Stats().DoubleReleases, uint64(1); actual != expected {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+
+				a, b := pool.Get(), pool.Get()
+				if a == b {
+					t.Errorf("Actual: %p; Expected: two different items", a)
+				}
+				if a != c && b != c {
+					t.Errorf("Actual: %p and %p; Expected: one of them %p", a, b, c)
+				}
+			})
+		}
+	}
+}
+
+func TestPoolsOrder(t *testing.T) {
+	for _, impl := range implementations {
+		for _, tc := range []struct {
+			name   string
+			order  typed.Configurator[int]
+			expect []int
+		}{
+			{"FIFO", typed.Order[int](typed.FIFO), []int{1, 2, 3}},
+			{"LIFO", typed.Order[int](typed.LIFO), []int{3, 2, 1}},
+			{"Stack(true)", typed.Stack[int](true), []int{3, 2, 1}},
+			{"Stack(false)", typed.Stack[int](false), []int{1, 2, 3}},
+			{"Random", typed.Order[int](typed.Random), nil},
+		} {
+			t.Run(impl.name+"/"+tc.name, func(t *testing.T) {
+				pool, err := newPool(impl, typed.MaxSize[int](3), tc.order,
+					typed.Factory(func() (int, error) {
+						return 13, nil
+					}))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer pool.Close()
+
+				for i := 1; i <= 3; i++ {
+					pool.Put(i)
+				}
+				var items []int
+				for i := 0; i < 3; i++ {
+					items = append(items, pool.Get())
+				}
+				if tc.expect == nil {
+					sort.Ints(items) // any order will do, so long as no item is lost
+					tc.expect = []int{1, 2, 3}
+				}
+				if actual, expected := items, tc.expect; !reflect.DeepEqual(actual, expected) {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+			})
+		}
+	}
+}
+
+func TestPoolsLIFOLetsIdleItemsExpire(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.MaxSize[int](3), typed.Order[int](typed.LIFO),
+				typed.MaxIdleTime[int](50*time.Millisecond),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			for i := 1; i <= 3; i++ {
+				pool.Put(i)
+			}
+			// Keep using one item, while the others remain idle.
+			for deadline := time.Now().Add(200 * time.Millisecond); time.Now().Before(deadline); {
+				pool.Put(pool.Get())
+				time.Sleep(5 * time.Millisecond)
+			}
+			if actual, expected := pool.Stats().Idle, 3; actual >= expected {
+				t.Errorf("Actual: %#v; Expected: fewer than %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsErrorWithInvalidOrder(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Order[int](typed.Ordering(42)),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}))
+			if pool != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+			}
+			if err == nil || !strings.Contains(err.Error(), "Ordering(42)") {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "Ordering(42)")
+			}
+		})
+	}
+}
+
+func TestPoolsCloseErrorRecordsEachItem(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int
+			errOdd := errors.New("odd")
+			pool, err := newPool(impl, typed.Size[int](4),
+				typed.Factory(func() (int, error) {
+					factoryInvoked++
+					return factoryInvoked, nil
+				}),
+				typed.Close(func(item int) error {
+					if item%2 == 1 {
+						return fmt.Errorf("cannot close %d: %w", item, errOdd)
+					}
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = pool.Close()
+			var ce *typed.CloseError[int]
+			if !errors.As(err, &ce) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, ce)
+			}
+			if !errors.Is(err, errOdd) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, errOdd)
+			}
+			if actual, expected := ce.Items, []int{1, 3}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := len(ce.Errs), 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := err.Error(), "cannot close 1: odd, cannot close 3: odd"; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsTryAcquireReturnsErrExhausted(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Size[int](1),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			lease, err := pool.TryAcquire()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other, err := pool.TryAcquire(); other != nil || err != typed.ErrExhausted {
+				t.Errorf("Actual: %#v, %#v; Expected: %#v", other, err, typed.ErrExhausted)
+			}
+			_ = lease.Release()
+		})
+	}
+}
+
+func TestPoolsFactoryFailureClosesCreatedItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int
+			var closed []int
+			errFactory := errors.New("factory")
+			errClose := errors.New("close")
+			pool, err := newPool(impl, typed.Size[int](10),
+				typed.Factory(func() (int, error) {
+					factoryInvoked++
+					if factoryInvoked == 7 {
+						return 0, errFactory
+					}
+					return factoryInvoked, nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					if item == 2 {
+						return errClose
+					}
+					return nil
+				}))
+			if pool != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+			}
+
+			var fe *typed.FillError
+			if !errors.As(err, &fe) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, fe)
+			}
+			if actual, expected := fe.Index, 6; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if !errors.Is(err, errFactory) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, errFactory)
+			}
+			if !errors.Is(err, errClose) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, errClose)
+			}
+			var ce *typed.CloseError[int]
+			if !errors.As(err, &ce) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, ce)
+			}
+			if actual, expected := ce.Items, []int{2}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := closed, []int{1, 2, 3, 4, 5, 6}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFillConcurrency(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var inFlight, peak int32
+			pool, err := newPool(impl, typed.Size[int](12), typed.FillConcurrency[int](3),
+				typed.Factory(func() (int, error) {
+					n := atomic.AddInt32(&inFlight, 1)
+					defer atomic.AddInt32(&inFlight, -1)
+					for {
+						p := atomic.LoadInt32(&peak)
+						if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			if actual, expected := pool.Stats().Idle, 12; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := atomic.LoadInt32(&peak), int32(3); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFillTimeoutClosesCreatedItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var closed []int
+			var factoryInvoked int32
+			release := make(chan struct{})
+			_, err := newPool(impl, typed.Size[int](5), typed.FillTimeout[int](20*time.Millisecond),
+				typed.Factory(func() (int, error) {
+					n := int(atomic.AddInt32(&factoryInvoked, 1))
+					if n == 3 {
+						<-release // factory call outstanding when fill times out
+					}
+					return n, nil
+				}),
+				typed.Close(func(item int) error {
+					lock.Lock()
+					closed = append(closed, item)
+					lock.Unlock()
+					return nil
+				}))
+
+			var fe *typed.FillError
+			if !errors.As(err, &fe) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, fe)
+			}
+			if actual, expected := fe.Index, 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if !errors.Is(err, typed.ErrTimeout) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrTimeout)
+			}
+			lock.Lock()
+			if actual, expected := closed, []int{1, 2}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			lock.Unlock()
+
+			// item from the outstanding factory call is closed once the call returns
+			close(release)
+			for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+				lock.Lock()
+				n := len(closed)
+				lock.Unlock()
+				if n == 3 {
+					break
+				}
+			}
+			lock.Lock()
+			if actual, expected := closed, []int{1, 2, 3}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			lock.Unlock()
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(3); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFactoryRetry(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.FactoryRetry[int](typed.RetryPolicy{Attempts: 3, InitialDelay: time.Millisecond, Jitter: 0.5}),
+				typed.Factory(func() (int, error) {
+					if n := atomic.AddInt32(&factoryInvoked, 1); n < 3 {
+						return 0, errors.New("foo")
+					}
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			item, err := pool.GetContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := item, 13; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			st := pool.Stats()
+			if actual, expected := st.FactoryCalls, uint64(3); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.FactoryFailures, uint64(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.FactoryRetries, uint64(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFactoryBreaker(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			var fail int32 = 1
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.FactoryRetry[int](typed.RetryPolicy{BreakerThreshold: 2, BreakerCooldown: 20 * time.Millisecond}),
+				typed.Factory(func() (int, error) {
+					atomic.AddInt32(&factoryInvoked, 1)
+					if atomic.LoadInt32(&fail) == 1 {
+						return 0, errors.New("foo")
+					}
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			for i := 0; i < 2; i++ {
+				if _, err := pool.GetContext(context.Background()); err == nil || err.Error() != "foo" {
+					t.Errorf("Actual: %#v; Expected: %#v", err, "foo")
+				}
+			}
+			st := pool.Stats()
+			if actual, expected := st.Breaker, typed.BreakerOpen; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			if actual, expected := st.BreakerTrips, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// open breaker fails fast without calling the factory
+			_, err = pool.GetContext(context.Background())
+			var be *typed.BreakerOpenError
+			if !errors.As(err, &be) || !errors.Is(err, typed.ErrBreakerOpen) {
+				t.Errorf("Actual: %#v; Expected: %T", err, be)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// failed probe opens the breaker again
+			time.Sleep(30 * time.Millisecond)
+			if actual, expected := pool.Stats().Breaker, typed.BreakerHalfOpen; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			if _, err := pool.GetContext(context.Background()); err == nil || err.Error() != "foo" {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "foo")
+			}
+			st = pool.Stats()
+			if actual, expected := st.Breaker, typed.BreakerOpen; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			if actual, expected := st.BreakerTrips, uint64(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// successful probe closes the breaker
+			time.Sleep(30 * time.Millisecond)
+			atomic.StoreInt32(&fail, 0)
+			item, err := pool.GetContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := item, 13; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Breaker, typed.BreakerClosed; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFactoryBreakerCanceledProbe(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var fail int32 = 1
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.FactoryRetry[int](typed.RetryPolicy{Attempts: 2, InitialDelay: 100 * time.Millisecond, BreakerThreshold: 1, BreakerCooldown: 10 * time.Millisecond}),
+				typed.Factory(func() (int, error) {
+					if atomic.LoadInt32(&fail) == 1 {
+						return 0, errors.New("foo")
+					}
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			if _, err := pool.GetContext(context.Background()); err == nil || err.Error() != "foo" {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "foo")
+			}
+			if actual, expected := pool.Stats().Breaker, typed.BreakerOpen; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+
+			// probe gives up while waiting to retry the factory
+			time.Sleep(20 * time.Millisecond)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if _, err := pool.GetContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, context.DeadlineExceeded)
+			}
+			if actual, expected := pool.Stats().Breaker, typed.BreakerHalfOpen; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+
+			// next caller probes instead
+			atomic.StoreInt32(&fail, 0)
+			item, err := pool.GetContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := item, 13; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Breaker, typed.BreakerClosed; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFactoryGivingUpWithCallerIsNotFailure(t *testing.T) {
+	const (
+		failing = iota
+		blocking
+		working
+	)
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var mode int32 = blocking
+			var factoryErrors int32
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.FactoryRetry[int](typed.RetryPolicy{BreakerThreshold: 2, BreakerCooldown: 10 * time.Millisecond}),
+				typed.Hooks(typed.LifecycleHooks[int]{
+					OnFactoryError: func(error) { atomic.AddInt32(&factoryErrors, 1) },
+				}),
+				typed.FactoryContext(func(ctx context.Context) (int, error) {
+					switch atomic.LoadInt32(&mode) {
+					case failing:
+						return 0, errors.New("foo")
+					case blocking:
+						<-ctx.Done()
+						return 0, ctx.Err()
+					}
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			getWithTimeout := func() error {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				_, err := pool.GetContext(ctx)
+				return err
+			}
+
+			// callers giving up neither count as factory failures nor trip the breaker
+			for i := 0; i < 2; i++ {
+				if err := getWithTimeout(); !errors.Is(err, typed.ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrTimeout)
+				}
+			}
+			st := pool.Stats()
+			if actual, expected := st.Breaker, typed.BreakerClosed; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			if actual, expected := st.FactoryFailures, uint64(0); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryErrors), int32(0); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// probe giving up with its caller lets the next caller probe
+			atomic.StoreInt32(&mode, failing)
+			for i := 0; i < 2; i++ {
+				_, _ = pool.GetContext(context.Background())
+			}
+			if actual, expected := pool.Stats().Breaker, typed.BreakerOpen; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.StoreInt32(&mode, blocking)
+			if err := getWithTimeout(); !errors.Is(err, typed.ErrTimeout) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrTimeout)
+			}
+			if actual, expected := pool.Stats().Breaker, typed.BreakerHalfOpen; actual != expected {
+				t.Errorf("Actual: %v; Expected: %v", actual, expected)
+			}
+			atomic.StoreInt32(&mode, working)
+			item, err := pool.GetContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := item, 13; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsTryGetDoesNotRetryFactory(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.FactoryRetry[int](typed.RetryPolicy{Attempts: 3, InitialDelay: time.Second}),
+				typed.Factory(func() (int, error) {
+					atomic.AddInt32(&factoryInvoked, 1)
+					return 0, errors.New("foo")
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			start := time.Now()
+			if _, ok := pool.TryGet(); ok {
+				t.Errorf("Actual: %#v; Expected: %#v", ok, false)
+			}
+			if _, err := pool.TryAcquire(); err == nil || err.Error() != "foo" {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "foo")
+			}
+			if actual, limit := time.Since(start), 500*time.Millisecond; actual > limit {
+				t.Errorf("Actual: %s; Expected: less than %s", actual, limit)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFactoryContextReceivesCallerContext(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var values []interface{}
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.FactoryContext(func(ctx context.Context) (int, error) {
+					values = append(values, ctx.Value(contextKey{}))
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			ctx := context.WithValue(context.Background(), contextKey{}, "caller")
+			if _, err := pool.GetContext(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := values, []interface{}{"caller"}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsFactoryContextCanceled(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.FactoryContext(func(ctx context.Context) (int, error) {
+					if ctx.Value(contextKey{}) != nil {
+						<-ctx.Done() // slow dial abandoned when caller's deadline expires
+						return 0, ctx.Err()
+					}
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), contextKey{}, "slow"), 10*time.Millisecond)
+			defer cancel()
+			if _, err := pool.GetContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, context.DeadlineExceeded)
+			}
+
+			// abandoned item's place in the pool is available
+			if item, ok := pool.TryGet(); !ok || item != 13 {
+				t.Errorf("Actual: %#v, %#v; Expected: %#v, %#v", item, ok, 13, true)
+			}
+		})
+	}
+}
+
+func TestPoolsFillTimeoutCancelsFactoryContext(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			canceled := make(chan struct{})
+			_, err := newPool(impl, typed.Size[int](2), typed.FillTimeout[int](10*time.Millisecond),
+				typed.ConstructionContext[int](context.WithValue(context.Background(), contextKey{}, "pool")),
+				typed.FactoryContext(func(ctx context.Context) (int, error) {
+					if ctx.Value(contextKey{}) != "pool" {
+						return 0, errors.New("not derived from construction context")
+					}
+					<-ctx.Done()
+					close(canceled)
+					return 0, ctx.Err()
+				}))
+			if !errors.Is(err, typed.ErrTimeout) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrTimeout)
+			}
+			select {
+			case <-canceled:
+			case <-time.After(time.Second):
+				t.Fatal("factory context not canceled")
+			}
+		})
+	}
+}
+
+func TestPoolsCloseCancelsBackgroundFactoryContext(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			started := make(chan struct{})
+			canceled := make(chan struct{})
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MinIdle[int](1), typed.MaxSize[int](2),
+				typed.FactoryContext(func(ctx context.Context) (int, error) {
+					if atomic.AddInt32(&factoryInvoked, 1) == 1 {
+						return 1, nil
+					}
+					close(started)
+					<-ctx.Done()
+					close(canceled)
+					return 0, ctx.Err()
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_ = pool.Get() // pool creates another idle item in the background
+			<-started
+			_ = pool.Close()
+			select {
+			case <-canceled:
+			case <-time.After(time.Second):
+				t.Fatal("factory context not canceled")
+			}
+		})
+	}
+}
+
+func TestPoolsResetOrDiscardReplacesRejectedItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked, closeInvoked int32
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](1),
+				typed.Factory(func() (*bytes.Buffer, error) {
+					atomic.AddInt32(&factoryInvoked, 1)
+					return makeBuffer()
+				}),
+				typed.ResetOrDiscard(func(bb *bytes.Buffer) error {
+					if bb.Cap() > defaultMaxKeep {
+						return errors.New("buffer too large")
+					}
+					bb.Reset()
+					return nil
+				}),
+				typed.Close(func(*bytes.Buffer) error {
+					atomic.AddInt32(&closeInvoked, 1)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			small := pool.Get()
+			small.WriteString("small")
+			pool.Put(small)
+			if actual, expected := atomic.LoadInt32(&closeInvoked), int32(0); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			large := pool.Get()
+			if large != small || large.Len() != 0 {
+				t.Errorf("Actual: %p with length %d; Expected: %p with length 0", large, large.Len(), small)
+			}
+			large.Write(make([]byte, 2*defaultMaxKeep))
+			pool.Put(large)
+			if actual, expected := atomic.LoadInt32(&closeInvoked), int32(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Rejections, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// rejected item is replaced by the factory
+			if bb := pool.Get(); bb == large || bb.Cap() > defaultMaxKeep {
+				t.Errorf("Actual: %p with capacity %d; Expected: new buffer", bb, bb.Cap())
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsRecoversResetPanic(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked, closeInvoked int32
+			var reported []*typed.PanicError
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](1),
+				typed.Factory(func() (*bytes.Buffer, error) {
+					atomic.AddInt32(&factoryInvoked, 1)
+					return makeBuffer()
+				}),
+				typed.Reset(func(bb *bytes.Buffer) {
+					if bb.Len() > 0 {
+						panic("boom")
+					}
+				}),
+				typed.Close(func(*bytes.Buffer) error {
+					atomic.AddInt32(&closeInvoked, 1)
+					return nil
+				}),
+				typed.OnPanic[*bytes.Buffer](func(pe *typed.PanicError) {
+					reported = append(reported, pe)
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			bb := pool.Get()
+			bb.WriteString("dirty")
+			pool.Put(bb)
+
+			if actual, expected := atomic.LoadInt32(&closeInvoked), int32(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Panics, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if len(reported) != 1 {
+				t.Fatalf("Actual: %#v; Expected: %#v", len(reported), 1)
+			}
+			if actual, expected := reported[0].Callback, "Reset"; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := reported[0].Value, "boom"; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if len(reported[0].Stack) == 0 {
+				t.Errorf("Actual: %#v; Expected: stack trace", reported[0].Stack)
+			}
+
+			// discarded item is replaced by the factory
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if replacement, err := pool.GetContext(ctx); err != nil || replacement == bb {
+				t.Errorf("Actual: %p, %#v; Expected: new buffer", replacement, err)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsRecoversFactoryPanic(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.Size[int](1), typed.MaxSize[int](2),
+				typed.Factory(func() (int, error) {
+					if atomic.AddInt32(&factoryInvoked, 1) > 1 {
+						panic(errors.New("boom"))
+					}
+					return 1, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			item := pool.Get()
+			_, err = pool.GetContext(context.Background())
+			var pe *typed.PanicError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, pe)
+			}
+			if actual, expected := pe.Callback, "Factory"; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := err.Error(), "pool Factory callback panicked: boom"; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().InUse, 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			pool.Put(item)
+			if actual, expected := pool.Get(), item; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsRecoversClosePanic(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Size[int](2),
+				typed.Factory(func() (int, error) { return 1, nil }),
+				typed.Close(func(int) error { panic("boom") }))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = pool.Close()
+			var ce *typed.CloseError[int]
+			if !errors.As(err, &ce) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, ce)
+			}
+			var pe *typed.PanicError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, pe)
+			}
+			if actual, expected := len(ce.Items), 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Panics, uint64(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsHooksReportLifecycleEvents(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var pool typed.Pool[int]
+			var events []string
+			var held time.Duration
+			var factoryInvoked int
+			errFactory := errors.New("factory failed")
+
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.Factory(func() (int, error) {
+					if factoryInvoked++; factoryInvoked > 1 {
+						return 0, errFactory
+					}
+					return 1, nil
+				}),
+				typed.Hooks(typed.LifecycleHooks[int]{
+					OnCreate: func(item int) {
+						events = append(events, fmt.Sprintf("create %d", item))
+					},
+					OnGet: func(item int, wait time.Duration) {
+						_ = pool.Stats() // hooks are called without holding the lock
+						events = append(events, fmt.Sprintf("get %d", item))
+					},
+					OnPut: func(item int, d time.Duration) {
+						if item == 1 {
+							held = d
+						}
+						events = append(events, fmt.Sprintf("put %d", item))
+					},
+					OnDiscard: func(item int, reason typed.DiscardReason) {
+						events = append(events, fmt.Sprintf("discard %d %s", item, reason))
+					},
+					OnFactoryError: func(err error) {
+						events = append(events, fmt.Sprintf("factory error %v", err))
+					},
+					OnClose: func(err error) {
+						events = append(events, fmt.Sprintf("close %v", err))
+					},
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			item := pool.Get()
+			time.Sleep(time.Millisecond)
+			pool.Put(item)
+			if held < time.Millisecond {
+				t.Errorf("Actual: %v; Expected: at least %v", held, time.Millisecond)
+			}
+			pool.Put(99) // pool has no room for foreign item
+			item = pool.Get()
+			if err := pool.Discard(item); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pool.GetContext(context.Background()); err != errFactory {
+				t.Errorf("Actual: %#v; Expected: %#v", err, errFactory)
+			}
+			if err := pool.Close(); err != nil {
+				t.Fatal(err)
+			}
+			_ = pool.Close() // OnClose is called only the first time
+
+			expected := []string{
+				"create 1",
+				"get 1",
+				"put 1",
+				"discard 99 full",
+				"put 99",
+				"get 1",
+				"discard 1 caller",
+				"factory error factory failed",
+				"close <nil>",
+			}
+			if !reflect.DeepEqual(events, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", events, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsErrorWithoutFactory(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool[*bytes.Buffer](impl)
+			if pool != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+			}
+			if err != typed.ErrNoFactory {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrNoFactory)
+			}
+		})
+	}
+}
+
+func TestPoolsErrorWithNonPositiveSize(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Factory(makeBuffer), typed.Size[*bytes.Buffer](0))
+			if pool != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+			}
+			if !errors.Is(err, typed.ErrInvalidSize) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrInvalidSize)
+			}
+		})
+	}
+}
+
+func TestPoolsCreatesSizeItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var size = 42
+			var factoryInvoked int
+			_, err := newPool(impl, typed.Size[int](size),
+				typed.Factory(func() (int, error) {
+					factoryInvoked++
+					return factoryInvoked, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if actual, expected := factoryInvoked, size; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsMaxSizeCreatesItemsOnDemand(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](3),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(0); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			for i := 1; i <= 3; i++ {
+				if actual, expected := pool.Get(), i; actual != expected {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if _, err := pool.GetContext(ctx); !errors.Is(err, typed.ErrTimeout) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrTimeout)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(3); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			pool.Put(2)
+			if actual, expected := pool.Get(), 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsMaxSizeFactoryErrorFreesSlot(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var fail int32 = 1
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.Factory(func() (int, error) {
+					if atomic.LoadInt32(&fail) == 1 {
+						return 0, errors.New("foo")
+					}
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := pool.GetContext(context.Background()); err == nil || err.Error() != "foo" {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "foo")
+			}
+			atomic.StoreInt32(&fail, 0)
+			if item, err := pool.GetContext(context.Background()); err != nil || item != 13 {
+				t.Errorf("Actual: %#v, %#v; Expected: %#v, %#v", item, err, 13, nil)
+			}
+		})
+	}
+}
+
+func TestPoolsMinIdleKeepsItemsWarm(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MinIdle[int](2), typed.MaxSize[int](5),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			_ = pool.Get()
+			_ = pool.Get()
+
+			// pool creates two more items in the background
+			deadline := time.Now().Add(time.Second)
+			for atomic.LoadInt32(&factoryInvoked) < 4 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(4); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsErrorWithMinIdleGreaterThanMaxSize(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Factory(makeBuffer), typed.MinIdle[*bytes.Buffer](3), typed.MaxSize[*bytes.Buffer](2))
+			if pool != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+			}
+			if err == nil {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "not nil")
+			}
+		})
+	}
+}
+
+func TestPoolsMaxIdleTimeClosesIdleItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked, closeInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](2), typed.MaxIdleTime[int](10*time.Millisecond),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(_ int) error {
+					atomic.AddInt32(&closeInvoked, 1)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			a, b := pool.Get(), pool.Get()
+			pool.Put(a)
+			pool.Put(b)
+
+			deadline := time.Now().Add(time.Second)
+			for atomic.LoadInt32(&closeInvoked) < 2 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if actual, expected := atomic.LoadInt32(&closeInvoked), int32(2); actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// expired items are replaced on demand
+			if actual, expected := pool.Get(), 3; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsMaxLifetimeClosesReleasedItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked, closeInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](1), typed.MaxLifetime[int](time.Hour),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(_ int) error {
+					atomic.AddInt32(&closeInvoked, 1)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			item := pool.Get()
+			pool.Put(item)
+			if actual, expected := atomic.LoadInt32(&closeInvoked), int32(0); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			pool, err = newPool(impl, typed.MaxSize[int](1), typed.MaxLifetime[int](10*time.Millisecond),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(_ int) error {
+					atomic.AddInt32(&closeInvoked, 1)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			item = pool.Get()
+			time.Sleep(20 * time.Millisecond)
+			pool.Put(item)
+			if actual, expected := atomic.LoadInt32(&closeInvoked), int32(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Get(), 3; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsErrorWithExpiryJitterNotLessThanMaxIdleTime(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Factory(makeBuffer), typed.MaxIdleTime[*bytes.Buffer](time.Second), typed.ExpiryJitter[*bytes.Buffer](time.Second))
+			if pool != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", pool, nil)
+			}
+			if err == nil {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "not nil")
+			}
+		})
+	}
+}
+
+func TestPoolsValidateOnGetDiscardsInvalidItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			var closed []int
+			pool, err := newPool(impl, typed.MaxSize[int](2),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return nil
+				}),
+				typed.ValidateOnGet(func(item int) error {
+					if item == 1 {
+						return errors.New("foo")
+					}
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// newly created items are not validated
+			if actual, expected := pool.Get(), 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			pool.Put(1)
+
+			if actual, expected := pool.Get(), 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := len(closed), 1; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := closed[0], 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().ValidationFailures, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsValidateOnPutDiscardsInvalidItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			var closed []int
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return nil
+				}),
+				typed.ValidateOnPut(func(item int) error {
+					if item%2 == 1 {
+						return errors.New("foo")
+					}
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pool.Put(pool.Get())
+			if actual, expected := len(closed), 1; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().ValidationFailures, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// released item's place in the pool is available for a replacement
+			if actual, expected := pool.Get(), 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			pool.Put(2)
+			if actual, expected := len(closed), 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsTrackBorrowersListsOutstandingItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Size[int](2), typed.TrackBorrowers[int](true),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			item := pool.Get()
+			time.Sleep(10 * time.Millisecond)
+
+			borrows := pool.Outstanding(time.Millisecond)
+			if actual, expected := len(borrows), 1; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := borrows[0].Item, 13; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := borrows[0].StackTrace(), "TestPoolsTrackBorrowersListsOutstandingItems"; !strings.Contains(actual, expected) {
+				t.Errorf("Actual: %#v; Expected to contain: %#v", actual, expected)
+			}
+			if actual, expected := len(pool.Outstanding(time.Hour)), 0; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			pool.Put(item)
+			if actual, expected := len(pool.Outstanding(0)), 0; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsWithoutTrackBorrowersListsNothing(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Size[int](1),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			_ = pool.Get()
+			if actual := pool.Outstanding(0); actual != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, nil)
+			}
+		})
+	}
+}
+
+func TestPoolsLeaseRejectsSecondRelease(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](1), typed.Factory(makeBuffer))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			lease, err := pool.Acquire()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := lease.Release(); err != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", err, nil)
+			}
+			if err := lease.Release(); err != typed.ErrReleased {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrReleased)
+			}
+			if err := lease.Discard(); err != typed.ErrReleased {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrReleased)
+			}
+
+			st := pool.Stats()
+			if actual, expected := st.Idle, 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.Puts, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.DoubleReleases, uint64(2); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// only one caller may hold the single buffer
+			first := pool.Get()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if _, err := pool.GetContext(ctx); !errors.Is(err, typed.ErrTimeout) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrTimeout)
+			}
+			pool.Put(first)
+		})
+	}
+}
+
+func TestPoolsLeaseDiscardClosesItem(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			var closed []int
+			pool, err := newPool(impl, typed.Size[int](1),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return errors.New("close error")
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			lease, err := pool.Acquire()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := lease.Item(), 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if err := lease.Discard(); err == nil || err.Error() != "close error" {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "close error")
+			}
+			if actual, expected := len(closed), 1; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if err := lease.Release(); err != typed.ErrReleased {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrReleased)
+			}
+
+			// discarded item's place in the pool is filled by a replacement
+			lease, err = pool.Acquire()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := lease.Item(), 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if err := lease.Release(); err != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", err, nil)
+			}
+			_ = pool.Close()
+			if actual, expected := closed, []int{1, 2}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsAcquireAfterClose(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Size[int](1),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = pool.Close()
+
+			if lease, err := pool.Acquire(); lease != nil || err != typed.ErrClosed {
+				t.Errorf("Actual: %#v, %#v; Expected: %#v", lease, err, typed.ErrClosed)
+			}
+		})
+	}
+}
+
+func TestPoolsDiscardReplacesItem(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			var closed []int
+			pool, err := newPool(impl, typed.Size[int](2),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			item := pool.Get()
+			if err := pool.Discard(item); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := closed, []int{item}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// pool filled during initialization creates a replacement right away
+			for deadline := time.Now().Add(time.Second); pool.Stats().Idle < 2 && time.Now().Before(deadline); {
+				time.Sleep(time.Millisecond)
+			}
+			st := pool.Stats()
+			if actual, expected := st.Idle, 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.InUse, 0; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(3); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsDiscardFreesPlaceForLazyReplacement(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			if err := pool.Discard(pool.Get()); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := atomic.LoadInt32(&factoryInvoked), int32(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			item, err := pool.GetContext(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := item, 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsDiscardRejectsItemNotCheckedOut(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var closed []int
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.Size[int](2),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			// foreign item
+			if err := pool.Discard(42); err != typed.ErrNotCheckedOut {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrNotCheckedOut)
+			}
+
+			// item discarded twice
+			a := pool.Get()
+			if err := pool.Discard(a); err != nil {
+				t.Fatal(err)
+			}
+			if err := pool.Discard(a); err != typed.ErrNotCheckedOut {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrNotCheckedOut)
+			}
+
+			// item discarded after being released back to the pool
+			b := pool.Get()
+			pool.Put(b)
+			if err := pool.Discard(b); err != typed.ErrNotCheckedOut {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrNotCheckedOut)
+			}
+
+			if actual, expected := closed, []int{a}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			st := pool.Stats()
+			if actual, expected := st.DoubleReleases, uint64(3); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// neither the discarded item nor a duplicate of the idle item is handed out
+			c, d := pool.Get(), pool.Get()
+			if c == a || d == a || c == d {
+				t.Errorf("Actual: %#v and %#v; Expected: two items other than %#v", c, d, a)
+			}
+		})
+	}
+}
+
+func TestPoolsTryGetDoesNotBlock(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](1),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// creates a new item when pool has room for one
+			item, ok := pool.TryGet()
+			if !ok || item != 1 {
+				t.Errorf("Actual: %#v, %#v; Expected: %#v, %#v", item, ok, 1, true)
+			}
+			if item, ok := pool.TryGet(); ok || item != 0 {
+				t.Errorf("Actual: %#v, %#v; Expected: %#v, %#v", item, ok, 0, false)
+			}
+			if actual, expected := pool.Stats().Waits, uint64(0); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			pool.Put(item)
+			if item, ok := pool.TryGet(); !ok || item != 1 {
+				t.Errorf("Actual: %#v, %#v; Expected: %#v, %#v", item, ok, 1, true)
+			}
+
+			_ = pool.Close()
+			if _, ok := pool.TryGet(); ok {
+				t.Errorf("Actual: %#v; Expected: %#v", ok, false)
+			}
+		})
+	}
+}
+
+func TestPoolsTryPutClosesItemWhenFull(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var closed []int
+			pool, err := newPool(impl, typed.Size[int](1),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			if actual, expected := pool.TryPut(42), false; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := closed, []int{42}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			item := pool.Get()
+			if actual, expected := pool.TryPut(item), true; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := len(closed), 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Idle, 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsOverflowCreatesTemporaryItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			var closed []int
+			pool, err := newPool(impl, typed.Size[int](1), typed.Overflow[int](1),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			first := pool.Get()
+			extra := pool.Get() // does not block
+			if actual, expected := extra, 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if _, ok := pool.TryGet(); ok {
+				t.Errorf("Actual: %#v; Expected: %#v", ok, false)
+			}
+			st := pool.Stats()
+			if actual, expected := st.Extra, 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.Overflows, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// temporary item released back to a full pool is dropped
+			pool.Put(extra)
+			pool.Put(first)
+			if actual, expected := closed, []int{2}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			st = pool.Stats()
+			if actual, expected := st.Idle, 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.Extra, 0; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsOverflowKeepsTemporaryItemWhenRoom(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var factoryInvoked int32
+			var closed []int
+			pool, err := newPool(impl, typed.MaxSize[int](1), typed.Overflow[int](1),
+				typed.Factory(func() (int, error) {
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(item int) error {
+					closed = append(closed, item)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			first := pool.Get()
+			extra := pool.Get()
+			if err := pool.Discard(first); err != nil {
+				t.Fatal(err)
+			}
+			pool.Put(extra)
+			if actual, expected := closed, []int{1}; !reflect.DeepEqual(actual, expected) {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Get(), extra; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().Extra, 0; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsStats(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var fail int32
+			var factoryInvoked int32
+			pool, err := newPool(impl, typed.MaxSize[int](3),
+				typed.Factory(func() (int, error) {
+					if atomic.LoadInt32(&fail) == 1 {
+						return 0, errors.New("foo")
+					}
+					return int(atomic.AddInt32(&factoryInvoked, 1)), nil
+				}),
+				typed.Close(func(_ int) error {
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			a, b := pool.Get(), pool.Get()
+			pool.Put(a)
+
+			st := pool.Stats()
+			if actual, expected := st.Capacity, 3; actual != expected {
+				t.Errorf("Capacity: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.Idle, 1; actual != expected {
+				t.Errorf("Idle: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.InUse, 1; actual != expected {
+				t.Errorf("InUse: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.Gets, uint64(2); actual != expected {
+				t.Errorf("Gets: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.Puts, uint64(1); actual != expected {
+				t.Errorf("Puts: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.FactoryCalls, uint64(2); actual != expected {
+				t.Errorf("FactoryCalls: Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			atomic.StoreInt32(&fail, 1)
+			_ = pool.Get() // takes idle item
+			if _, err := pool.GetContext(context.Background()); err == nil {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "not nil")
+			}
+			if actual, expected := pool.Stats().FactoryFailures, uint64(1); actual != expected {
+				t.Errorf("FactoryFailures: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			atomic.StoreInt32(&fail, 0)
+			_ = pool.Get() // pool now has its maximum number of items checked out
+
+			got := make(chan int)
+			go func() {
+				got <- pool.Get()
+			}()
+			deadline := time.Now().Add(time.Second)
+			for pool.Stats().Waiters == 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if actual, expected := pool.Stats().Waiters, 1; actual != expected {
+				t.Fatalf("Waiters: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			pool.Put(b)
+			if actual, expected := <-got, b; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			st = pool.Stats()
+			if actual, expected := st.Waiters, 0; actual != expected {
+				t.Errorf("Waiters: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := st.Waits, uint64(1); actual != expected {
+				t.Errorf("Waits: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if st.WaitDuration <= 0 {
+				t.Errorf("WaitDuration: Actual: %#v; Expected: %s", st.WaitDuration, "greater than 0")
+			}
+			if actual, expected := st.WaitTimes.Count(), st.Waits; actual != expected {
+				t.Errorf("WaitTimes: Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if st.WaitTimes.Quantile(1) < st.WaitDuration {
+				t.Errorf("WaitTimes: Actual: %s; Expected: %s", st.WaitTimes.Quantile(1), "at least WaitDuration")
+			}
+
+			pool.Put(a)
+			if err := pool.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := pool.Stats().CloseCalls, uint64(1); actual != expected {
+				t.Errorf("CloseCalls: Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsReturnsTypedItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var reset []int
+			pool, err := newPool(impl, typed.Size[int](2),
+				typed.Factory(func() (int, error) {
+					return 13, nil
+				}),
+				typed.Reset(func(item int) {
+					reset = append(reset, item)
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			item, err := pool.GetContext(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := item, 13; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			pool.Put(item)
+			if actual, expected := len(reset), 1; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := reset[0], 13; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsInvokesClose(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var closeInvoked int
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](1),
+				typed.Factory(makeBuffer),
+				typed.Close(func(_ *bytes.Buffer) error {
+					closeInvoked++
+					return errors.New("foo")
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := pool.Close(); err == nil || err.Error() != "foo" {
+				t.Errorf("Actual: %#v; Expected: %#v", err, "foo")
+			}
+			if actual, expected := closeInvoked, 1; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPoolsCloseWakesBlockedGet(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](1), typed.Factory(makeBuffer))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = pool.Get()
+
+			errs := make(chan error)
+			go func() {
+				_, err := pool.GetContext(context.Background())
+				errs <- err
+			}()
+			time.Sleep(10 * time.Millisecond)
+			if err := pool.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case err := <-errs:
+				if err != typed.ErrClosed {
+					t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrClosed)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("blocked GetContext not woken by Close")
+			}
+
+			if _, err := pool.GetContext(context.Background()); err != typed.ErrClosed {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrClosed)
+			}
+			if item := pool.Get(); item != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", item, nil)
+			}
+		})
+	}
+}
+
+func TestPoolsPutAfterCloseInvokesClose(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var closed []*bytes.Buffer
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](2),
+				typed.Factory(makeBuffer),
+				typed.Close(func(bb *bytes.Buffer) error {
+					closed = append(closed, bb)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := pool.Get()
+			if err := pool.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if actual, expected := len(closed), 1; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if closed[0] == item {
+				t.Errorf("Close invoked on checked out item")
+			}
+
+			pool.Put(item)
+			if actual, expected := len(closed), 2; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if closed[1] != item {
+				t.Errorf("Actual: %#v; Expected: %#v", closed[1], item)
+			}
+
+			// item that was not checked out is closed too
+			foreign := new(bytes.Buffer)
+			pool.Put(foreign)
+			if actual, expected := len(closed), 3; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if closed[2] != foreign {
+				t.Errorf("Actual: %#v; Expected: %#v", closed[2], foreign)
+			}
+
+			// idle item already closed by Close is not closed again
+			pool.Put(closed[0])
+			if actual, expected := len(closed), 3; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if actual, expected := pool.Stats().DoubleReleases, uint64(1); actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			if err := pool.Close(); err != nil {
+				t.Errorf("Actual: %#v; Expected: %#v", err, nil)
+			}
+		})
+	}
+}
+
+func TestPoolsShutdownWaitsForCheckedOutItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var lock sync.Mutex
+			var closed []*bytes.Buffer
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](2),
+				typed.Factory(makeBuffer),
+				typed.Close(func(bb *bytes.Buffer) error {
+					lock.Lock()
+					closed = append(closed, bb)
+					lock.Unlock()
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := pool.Get()
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				pool.Put(item)
+			}()
+			if err := pool.Shutdown(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if actual, expected := len(closed), 2; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if closed[1] != item {
+				t.Errorf("Actual: %#v; Expected: %#v", closed[1], item)
+			}
+			if _, err := pool.GetContext(context.Background()); err != typed.ErrClosed {
+				t.Errorf("Actual: %#v; Expected: %#v", err, typed.ErrClosed)
+			}
+		})
+	}
+}
+
+func TestPoolsShutdownForceClosesOutstandingItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var closed []*bytes.Buffer
+			pool, err := newPool(impl, typed.Size[*bytes.Buffer](2),
+				typed.Factory(makeBuffer),
+				typed.Close(func(bb *bytes.Buffer) error {
+					closed = append(closed, bb)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			item := pool.Get()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			err = pool.Shutdown(ctx)
+
+			var oe *typed.OutstandingError[*bytes.Buffer]
+			if !errors.As(err, &oe) {
+				t.Fatalf("Actual: %#v; Expected: %T", err, oe)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Actual: %#v; Expected: %#v", err, context.DeadlineExceeded)
+			}
+			if actual, expected := len(oe.Items), 1; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+			if oe.Items[0] != item {
+				t.Errorf("Actual: %#v; Expected: %#v", oe.Items[0], item)
+			}
+			if actual, expected := len(closed), 2; actual != expected {
+				t.Fatalf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+
+			// returning a force closed item does not close it a second time
+			pool.Put(item)
+			if actual, expected := len(closed), 2; actual != expected {
+				t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+			}
+		})
+	}
+}
+
+func TestPools(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			pool, err := newPool(impl, typed.Factory(makeBuffer), typed.Reset(resetBuffer), typed.Close(closeBuffer), typed.Size[*bytes.Buffer](lowCap))
+			if err != nil {
+				t.Fatal(err)
+			}
+			test(t, pool)
+		})
+	}
+}
+
+func TestPoolsOverflowWithUntrackedItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var closeInvoked int32
+			pool, err := newPool(impl, typed.Size[[]byte](1), typed.Overflow[[]byte](1),
+				typed.Factory(func() ([]byte, error) {
+					return make([]byte, 0, 8), nil
+				}),
+				typed.Close(func([]byte) error {
+					atomic.AddInt32(&closeInvoked, 1)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			for i := 0; i < 3; i++ {
+				a, b := pool.Get(), pool.Get()
+				pool.Put(a)
+				pool.Put(b) // byte slices cannot be told apart, so either is the temporary item
+
+				st := pool.Stats()
+				if actual, expected := [3]int{st.Idle, st.InUse, st.Extra}, [3]int{1, 0, 0}; actual != expected {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+				if actual, expected := atomic.LoadInt32(&closeInvoked), int32(i+1); actual != expected {
+					t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestPoolsMaxLifetimeClosesUntrackedItems(t *testing.T) {
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			var closeInvoked int32
+			pool, err := newPool(impl, typed.Size[[]byte](1), typed.MaxLifetime[[]byte](20*time.Millisecond),
+				typed.Factory(func() ([]byte, error) {
+					return make([]byte, 0, 8), nil
+				}),
+				typed.Close(func([]byte) error {
+					atomic.AddInt32(&closeInvoked, 1)
+					return nil
+				}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pool.Close()
+
+			// Keep the item busy, so only Put may find it has exceeded its lifetime.
+			for deadline := time.Now().Add(100 * time.Millisecond); time.Now().Before(deadline); {
+				pool.Put(pool.Get())
+			}
+			if actual := atomic.LoadInt32(&closeInvoked); actual < 2 {
+				t.Errorf("Actual: %#v; Expected: at least %#v", actual, 2)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package typed
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrBreakerOpen is wrapped by the *BreakerOpenError returned instead of calling the factory while
+// the circuit breaker of a FactoryRetry policy is open.
+var ErrBreakerOpen = errors.New("pool factory circuit breaker open")
+
+// BreakerOpenError is returned instead of calling the factory while the circuit breaker of a
+// FactoryRetry policy is open.
+type BreakerOpenError struct {
+	Until time.Time // when the breaker next allows a probing factory call
+	Err   error     // error returned by the factory call that opened the breaker
+}
+
+func (e *BreakerOpenError) Error() string {
+	return fmt.Sprintf("%s until %s: %s", ErrBreakerOpen, e.Until.Format(time.RFC3339Nano), e.Err)
+}
+
+func (e *BreakerOpenError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BreakerOpenError) Is(target error) bool {
+	return target == ErrBreakerOpen
+}
+
+// RetryPolicy describes how a pool retries failed factory calls, and when it stops calling the
+// factory altogether. The zero value calls the factory once for each item, and never opens the
+// circuit breaker.
+type RetryPolicy struct {
+	Attempts     int           // maximum number of factory calls for each item, including the first
+	InitialDelay time.Duration // delay before the first retry
+	MaxDelay     time.Duration // maximum delay before a retry, or zero for no maximum
+	Multiplier   float64       // factor by which each delay exceeds the previous one, or zero for 2
+	Jitter       float64       // fraction of each delay that is randomly subtracted, from 0 to 1
+
+	// BreakerThreshold is the number of consecutive items the factory fails to create, after
+	// exhausting their attempts, that opens the circuit breaker, or zero to never open it. While the
+	// breaker is open, the pool returns a *BreakerOpenError rather than calling the factory.
+	BreakerThreshold int
+
+	// BreakerCooldown is how long the breaker remains open before becoming half-open, when it
+	// allows a single probing factory call. When the probe succeeds, the breaker closes, otherwise
+	// it opens again.
+	BreakerCooldown time.Duration
+}
+
+// FactoryRetry specifies how the pool retries failed factory calls using exponential backoff with
+// jitter, and when a circuit breaker stops calling the factory altogether, so callers fail fast
+// rather than waiting on a factory that keeps failing. TryGet and TryAcquire, which must not block,
+// call the factory only once.
+func FactoryRetry[T any](policy RetryPolicy) Configurator[T] {
+	return func(pc *config[T]) error {
+		if policy.Attempts < 0 || policy.InitialDelay < 0 || policy.MaxDelay < 0 || policy.Multiplier < 0 {
+			return fmt.Errorf("pool factory retry attempts, delays, and multiplier must not be negative: %+v", policy)
+		}
+		if policy.Jitter < 0 || policy.Jitter > 1 {
+			return fmt.Errorf("pool factory retry jitter must be from 0 to 1: %g", policy.Jitter)
+		}
+		if policy.BreakerThreshold < 0 {
+			return fmt.Errorf("pool factory breaker threshold must not be negative: %d", policy.BreakerThreshold)
+		}
+		if policy.BreakerThreshold > 0 && policy.BreakerCooldown <= 0 {
+			return fmt.Errorf("pool factory breaker cooldown must be greater than 0: %s", policy.BreakerCooldown)
+		}
+		pc.retry = policy
+		return nil
+	}
+}
+
+// attempts returns the maximum number of factory calls for each item.
+func (rp *RetryPolicy) attempts() int {
+	if rp.Attempts < 1 {
+		return 1
+	}
+	return rp.Attempts
+}
+
+// delay returns how long to wait before the specified retry, counting from 1.
+func (rp *RetryPolicy) delay(retry int) time.Duration {
+	multiplier := rp.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	d := float64(rp.InitialDelay)
+	for i := 1; i < retry && (rp.MaxDelay == 0 || d < float64(rp.MaxDelay)); i++ {
+		d *= multiplier
+	}
+	if rp.MaxDelay > 0 && d > float64(rp.MaxDelay) {
+		d = float64(rp.MaxDelay)
+	}
+	return time.Duration(d * (1 - rp.Jitter*rand.Float64()))
+}
+
+// BreakerState is the state of the circuit breaker of a FactoryRetry policy.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // factory is called as needed
+	BreakerOpen                         // factory is not called
+	BreakerHalfOpen                     // factory is called once to probe whether it recovered
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// breaker tracks consecutive factory failures to decide whether the factory may be called. The
+// zero value is a closed breaker ready for use.
+type breaker struct {
+	lock     sync.Mutex // protects the following fields
+	state    BreakerState
+	failures int       // consecutive items the factory failed to create
+	until    time.Time // when an open breaker becomes half-open
+	err      error     // error from the factory call that opened the breaker
+}
+
+// allow returns nil when the factory may be called, and otherwise a *BreakerOpenError. Only one
+// caller at a time is allowed to probe a half-open breaker, and allow returns true to that caller,
+// which must then settle the probe by calling succeeded, failed, or abandoned.
+func (br *breaker) allow() (bool, error) {
+	br.lock.Lock()
+	defer br.lock.Unlock()
+	switch br.state {
+	case BreakerOpen:
+		if time.Now().Before(br.until) {
+			return false, &BreakerOpenError{Until: br.until, Err: br.err}
+		}
+		br.state = BreakerHalfOpen
+		return true, nil
+	case BreakerHalfOpen:
+		return false, &BreakerOpenError{Until: br.until, Err: br.err}
+	default:
+		return false, nil
+	}
+}
+
+// abandoned records the caller probing a half-open breaker giving up before the factory either
+// created an item or exhausted its attempts, which lets the next caller probe instead.
+func (br *breaker) abandoned() {
+	br.lock.Lock()
+	if br.state == BreakerHalfOpen {
+		br.state = BreakerOpen
+		br.until = time.Now()
+	}
+	br.lock.Unlock()
+}
+
+// succeeded records the factory creating an item, which closes the breaker.
+func (br *breaker) succeeded() {
+	br.lock.Lock()
+	br.state = BreakerClosed
+	br.failures = 0
+	br.lock.Unlock()
+}
+
+// failed records the factory failing to create an item with err, returning true when this opens the
+// breaker.
+func (br *breaker) failed(rp *RetryPolicy, err error) bool {
+	if rp.BreakerThreshold == 0 {
+		return false
+	}
+	br.lock.Lock()
+	defer br.lock.Unlock()
+	br.failures++
+	if br.state != BreakerHalfOpen && br.failures < rp.BreakerThreshold {
+		return false
+	}
+	br.state = BreakerOpen
+	br.until = time.Now().Add(rp.BreakerCooldown)
+	br.err = err
+	return true
+}
+
+// current returns the state of the breaker.
+func (br *breaker) current() BreakerState {
+	br.lock.Lock()
+	defer br.lock.Unlock()
+	if br.state == BreakerOpen && !time.Now().Before(br.until) {
+		return BreakerHalfOpen // next factory call will probe
+	}
+	return br.state
+}
@@ -0,0 +1,112 @@
+package typed
+
+// SemaphorePool implements the Pool interface, maintaining a pool of resources. Idle items are kept
+// in a slice in the order they were released, and callers blocked waiting for an item are woken one
+// at a time as items become available.
+type SemaphorePool[T any] struct {
+	condPool[T]
+}
+
+// NewSemaphore creates a new Pool. The factory method used to create new items for the Pool must be
+// specified using the typed.Factory method. Optionally, the pool size and a reset function can be
+// specified. When either MinIdle or MaxSize is specified, items are created on demand rather than
+// during initialization. When the factory fails while filling the pool, the items already created
+// are passed to the optional close function, and a *FillError is returned.
+//
+//	package main
+//
+//	import (
+//		"bytes"
+//		"errors"
+//		"fmt"
+//		"log"
+//		"math/rand"
+//		"sync"
+//
+//		"github.com/karrick/gopool/typed"
+//	)
+//
+//	const (
+//		bufSize  = 64 * 1024
+//		poolSize = 25
+//	)
+//
+//	func main() {
+//		const iterationCount = 1000
+//		const parallelCount = 100
+//
+//		makeBuffer := func() (*bytes.Buffer, error) {
+//			return bytes.NewBuffer(make([]byte, 0, bufSize)), nil
+//		}
+//
+//		resetBuffer := func(bb *bytes.Buffer) {
+//			bb.Reset()
+//		}
+//
+//		bp, err := typed.NewSemaphore(typed.Size[*bytes.Buffer](poolSize), typed.Factory(makeBuffer), typed.Reset(resetBuffer))
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//
+//		var wg sync.WaitGroup
+//		wg.Add(parallelCount)
+//
+//		for i := 0; i < parallelCount; i++ {
+//			go func() {
+//				defer wg.Done()
+//
+//				for j := 0; j < iterationCount; j++ {
+//					if err := grabBufferAndUseIt(bp); err != nil {
+//						fmt.Println(err)
+//						return
+//					}
+//				}
+//			}()
+//		}
+//		wg.Wait()
+//	}
+//
+//	func grabBufferAndUseIt(pool typed.Pool[*bytes.Buffer]) error {
+//		// WARNING: Must ensure resource returns to pool otherwise gopool will deadlock once all
+//		// resources used.
+//		bb := pool.Get()
+//		defer pool.Put(bb) // IMPORTANT: defer here to ensure invoked even when subsequent code bails
+//
+//		for k := 0; k < bufSize; k++ {
+//			if rand.Intn(100000000) == 1 {
+//				return errors.New("random error to illustrate need to return resource to pool")
+//			}
+//			bb.WriteByte(byte(k % 256))
+//		}
+//		return nil
+//	}
+func NewSemaphore[T any](setters ...Configurator[T]) (Pool[T], error) {
+	pc, err := newConfig(setters)
+	if err != nil {
+		return nil, err
+	}
+	pool := new(SemaphorePool[T])
+	free := make(freeList[T], 0, pc.size)
+	if err := pool.start(pc, &free, true); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// freeList holds the idle entries of a SemaphorePool, in the order they were released.
+type freeList[T any] []entry[T]
+
+// len returns the number of idle entries.
+func (f *freeList[T]) len() int {
+	return len(*f)
+}
+
+// take removes and returns the idle entry chosen according to order.
+func (f *freeList[T]) take(order Ordering) entry[T] {
+	return takeIdle((*[]entry[T])(f), order)
+}
+
+// push adds e after the other idle entries.
+func (f *freeList[T]) push(e entry[T]) {
+	*f = append(*f, e)
+}
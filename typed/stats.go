@@ -0,0 +1,96 @@
+package typed
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats describes a pool, counting events since the pool was created.
+type Stats struct {
+	Capacity int // maximum number of items the pool may hold
+	Idle     int // number of items in the pool
+	InUse    int // number of items checked out of the pool
+	Waiters  int // number of callers blocked waiting for an item
+	Extra    int // number of temporary items created by Overflow that are checked out
+
+	Gets         uint64        // items handed to callers
+	Puts         uint64        // items released back to the pool
+	Waits        uint64        // requests for an item that blocked waiting for one
+	WaitDuration time.Duration // cumulative time spent blocked waiting for an item
+	WaitTimes    Histogram     // time spent by each request for an item that blocked waiting for one
+
+	FactoryCalls       uint64 // invocations of the factory function
+	FactoryFailures    uint64 // invocations of the factory function that returned an error
+	FactoryRetries     uint64 // invocations of the factory function retrying a failed invocation
+	CloseCalls         uint64 // invocations of the close function
+	ValidationFailures uint64 // items closed because they failed validation
+	Rejections         uint64 // items closed because ResetOrDiscard rejected them
+	Panics             uint64 // callbacks that panicked
+	DoubleReleases     uint64 // rejected attempts to release or discard an item or lease not checked out
+	Overflows          uint64 // temporary items created by Overflow beyond the pool's capacity
+
+	Breaker      BreakerState // state of the factory circuit breaker
+	BreakerTrips uint64       // times the factory circuit breaker opened
+}
+
+// counters tracks events of interest in a pool. Counters are updated atomically, so they may be
+// read without holding any pool lock. Every field is 64 bits wide, and base holds counters as its
+// first field, so that the fields are aligned for atomic access on 32-bit platforms.
+type counters struct {
+	waiters int64
+
+	gets         uint64
+	puts         uint64
+	waits        uint64
+	waitDuration int64
+	waitTimes    histogram
+
+	factoryCalls       uint64
+	factoryFailures    uint64
+	factoryRetries     uint64
+	closeCalls         uint64
+	validationFailures uint64
+	rejections         uint64
+	panics             uint64
+	doubleReleases     uint64
+	overflows          uint64
+	breakerTrips       uint64
+}
+
+// waiting records a caller beginning to block waiting for an item, and returns the time it began.
+func (c *counters) waiting() time.Time {
+	atomic.AddInt64(&c.waiters, 1)
+	return time.Now()
+}
+
+// waited records a caller no longer blocked waiting for an item, which began waiting at start.
+func (c *counters) waited(start time.Time) {
+	atomic.AddInt64(&c.waiters, -1)
+	d := time.Since(start)
+	atomic.AddUint64(&c.waits, 1)
+	atomic.AddInt64(&c.waitDuration, int64(d))
+	c.waitTimes.record(d)
+}
+
+// stats returns a Stats populated from the counters. The caller is responsible for populating the
+// fields that describe the items in the pool.
+func (c *counters) stats() Stats {
+	return Stats{
+		Waiters:            int(atomic.LoadInt64(&c.waiters)),
+		Gets:               atomic.LoadUint64(&c.gets),
+		Puts:               atomic.LoadUint64(&c.puts),
+		Waits:              atomic.LoadUint64(&c.waits),
+		WaitDuration:       time.Duration(atomic.LoadInt64(&c.waitDuration)),
+		WaitTimes:          c.waitTimes.snapshot(),
+		FactoryCalls:       atomic.LoadUint64(&c.factoryCalls),
+		FactoryFailures:    atomic.LoadUint64(&c.factoryFailures),
+		FactoryRetries:     atomic.LoadUint64(&c.factoryRetries),
+		CloseCalls:         atomic.LoadUint64(&c.closeCalls),
+		ValidationFailures: atomic.LoadUint64(&c.validationFailures),
+		Rejections:         atomic.LoadUint64(&c.rejections),
+		Panics:             atomic.LoadUint64(&c.panics),
+		DoubleReleases:     atomic.LoadUint64(&c.doubleReleases),
+		Overflows:          atomic.LoadUint64(&c.overflows),
+		BreakerTrips:       atomic.LoadUint64(&c.breakerTrips),
+	}
+}
@@ -0,0 +1,9 @@
+//go:build go1.20
+
+package typed
+
+// Unwrap returns the errors returned by the close function, so errors.Is and errors.As examine each
+// of them. Go releases before 1.20 rely on the Is and As methods instead.
+func (e *CloseError[T]) Unwrap() []error {
+	return e.Errs
+}
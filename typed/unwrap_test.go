@@ -0,0 +1,22 @@
+//go:build go1.20
+
+package typed_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/karrick/gopool/typed"
+)
+
+func TestCloseErrorUnwrapsEachError(t *testing.T) {
+	errFoo, errBar := errors.New("foo"), errors.New("bar")
+	ce := &typed.CloseError[int]{Items: []int{1, 2}, Errs: []error{errFoo, errBar}}
+
+	if actual, expected := len(ce.Unwrap()), 2; actual != expected {
+		t.Errorf("Actual: %#v; Expected: %#v", actual, expected)
+	}
+	if err := error(ce); !errors.Is(err, errFoo) || !errors.Is(err, errBar) {
+		t.Errorf("Actual: %#v; Expected: both %#v and %#v", err, errFoo, errBar)
+	}
+}